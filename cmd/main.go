@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"fmt"
@@ -24,6 +25,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/konflux-ci/tekton-kueue/pkg/common"
@@ -153,6 +155,9 @@ func (w *WebhookFlags) AddFlags(fs *flag.FlagSet) {
 type MutateFlags struct {
 	PipelineRunFile string
 	ConfigDir       string
+	ConfigFiles     []string
+	NamespaceFile   string
+	Now             string
 	ZapOptions      *zap.Options
 }
 
@@ -160,13 +165,32 @@ func (m *MutateFlags) AddFlags(fs *flag.FlagSet) {
 	fs.StringVar(&m.PipelineRunFile, "pipelinerun-file", "",
 		"Path to the file containing the PipelineRun definition (required)")
 	fs.StringVar(&m.ConfigDir, "config-dir", "",
-		"The directory that contains the configuration file for the tekton-kueue (required)")
+		"The directory that contains the configuration file for the tekton-kueue (required unless --config-file is set)")
+	fs.Var((*stringSliceFlag)(&m.ConfigFiles), "config-file",
+		"Path to a configuration file or config fragment file, merged like the fragment ConfigMaps. "+
+			"Can be repeated (optional)")
+	fs.StringVar(&m.NamespaceFile, "namespace-file", "",
+		"Path to a file containing the Namespace of the PipelineRun, exposed to CEL expressions and queueRouting selectors (optional)")
+	fs.StringVar(&m.Now, "now", "",
+		"RFC 3339 timestamp used as the current time by CEL expressions, e.g. 2025-06-02T09:30:00Z (optional)")
 	m.ZapOptions = &zap.Options{
 		Development: true,
 	}
 	m.ZapOptions.BindFlags(fs)
 }
 
+// stringSliceFlag is a flag that can be repeated, collecting its values.
+type stringSliceFlag []string
+
+func (f *stringSliceFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringSliceFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 func main() {
 	expectedSubcommands := "expected 'controller', 'webhook', or 'mutate' subcommand"
 	if len(os.Args) < 2 {
@@ -318,8 +342,17 @@ func runWebhook(args []string) {
 		setupLog.Error(err, "unable to create manager")
 		os.Exit(1)
 	}
+	// Register the metadata-only Namespace informer up front so it is
+	// started and synced together with the rest of the cache, instead of
+	// on the first admission request.
+	if _, err := mgr.GetCache().GetInformer(
+		context.Background(), webhookv1.NewNamespaceMetadata(), cache.BlockUntilSynced(false),
+	); err != nil {
+		setupLog.Error(err, "unable to create the Namespace informer")
+		os.Exit(1)
+	}
 	cfgStore := &webhookv1.ConfigStore{}
-	customDefaulter, err := webhookv1.NewCustomDefaulter(cfgStore)
+	customDefaulter, err := webhookv1.NewCustomDefaulter(cfgStore, webhookv1.NewNamespaceGetter(mgr.GetCache()))
 	if err != nil {
 		setupLog.Error(err, "unable to create custom defaulter")
 		os.Exit(1)
@@ -371,14 +404,30 @@ func runMutate(args []string) {
 		fs.Usage()
 		os.Exit(1)
 	}
-	if mutateFlags.ConfigDir == "" {
-		fmt.Fprintf(os.Stderr, "Error: --config-dir is required\n")
+	if mutateFlags.ConfigDir == "" && len(mutateFlags.ConfigFiles) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: --config-dir or --config-file is required\n")
 		fs.Usage()
 		os.Exit(1)
 	}
 
 	// Use the mutate package to perform the mutation
-	mutatedData, err := mutate.MutatePipelineRun(mutateFlags.PipelineRunFile, mutateFlags.ConfigDir)
+	var opts []mutate.Option
+	if len(mutateFlags.ConfigFiles) != 0 {
+		opts = append(opts, mutate.WithConfigFiles(mutateFlags.ConfigFiles...))
+	}
+	if mutateFlags.NamespaceFile != "" {
+		opts = append(opts, mutate.WithNamespaceFile(mutateFlags.NamespaceFile))
+	}
+	if mutateFlags.Now != "" {
+		now, err := time.Parse(time.RFC3339, mutateFlags.Now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --now must be an RFC 3339 timestamp: %v\n", err)
+			fs.Usage()
+			os.Exit(1)
+		}
+		opts = append(opts, mutate.WithNow(now))
+	}
+	mutatedData, err := mutate.MutatePipelineRun(mutateFlags.PipelineRunFile, mutateFlags.ConfigDir, opts...)
 	if err != nil {
 		setupLog.Error(err, "Failed to mutate PipelineRun")
 		os.Exit(1)
@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"slices"
 	"testing"
 	"time"
 )
@@ -115,10 +116,24 @@ func TestMutateFlags_AddFlags(t *testing.T) {
 			args: []string{
 				"--pipelinerun-file=/tmp/plr.yaml",
 				"--config-dir=/tmp/config",
+				"--now=2025-06-02T09:30:00Z",
 			},
 			expected: MutateFlags{
 				PipelineRunFile: "/tmp/plr.yaml",
 				ConfigDir:       "/tmp/config",
+				Now:             "2025-06-02T09:30:00Z",
+			},
+		},
+		{
+			name: "with config files",
+			args: []string{
+				"--pipelinerun-file=/tmp/plr.yaml",
+				"--config-file=/tmp/config.yaml",
+				"--config-file=/tmp/team-a.yaml",
+			},
+			expected: MutateFlags{
+				PipelineRunFile: "/tmp/plr.yaml",
+				ConfigFiles:     []string{"/tmp/config.yaml", "/tmp/team-a.yaml"},
 			},
 		},
 	}
@@ -140,6 +155,12 @@ func TestMutateFlags_AddFlags(t *testing.T) {
 			if flags.ConfigDir != tt.expected.ConfigDir {
 				t.Errorf("ConfigDir = %v, want %v", flags.ConfigDir, tt.expected.ConfigDir)
 			}
+			if !slices.Equal(flags.ConfigFiles, tt.expected.ConfigFiles) {
+				t.Errorf("ConfigFiles = %v, want %v", flags.ConfigFiles, tt.expected.ConfigFiles)
+			}
+			if flags.Now != tt.expected.Now {
+				t.Errorf("Now = %v, want %v", flags.Now, tt.expected.Now)
+			}
 		})
 	}
 }
@@ -0,0 +1,57 @@
+package cel
+
+import (
+	"hash/fnv"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// createBucketFunctions creates the bucket() and percent() helpers, which
+// assign strings to stable buckets for gradual rollouts. The hash is the
+// 64-bit FNV-1a hash of the UTF-8 bytes of the string, so that a string
+// always lands in the same bucket, in the webhook, in the mutate CLI and
+// across releases.
+func createBucketFunctions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("bucket",
+			cel.Overload("bucket_string_int_to_int",
+				[]*cel.Type{cel.StringType, cel.IntType},
+				cel.IntType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					value, valueOk := lhs.Value().(string)
+					count, countOk := rhs.Value().(int64)
+					if !valueOk || !countOk {
+						return types.NewErr("bucket function requires a string and an int")
+					}
+					if count <= 0 {
+						return types.NewErr("bucket count must be positive, got %d", count)
+					}
+					return types.Int(bucket(value, uint64(count)))
+				}),
+			),
+		),
+		cel.Function("percent",
+			cel.Overload("percent_string_to_int",
+				[]*cel.Type{cel.StringType},
+				cel.IntType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					value, ok := arg.Value().(string)
+					if !ok {
+						return types.NewErr("percent function requires a string argument")
+					}
+					return types.Int(bucket(value, 100))
+				}),
+			),
+		),
+	}
+}
+
+// bucket returns the bucket of value among count buckets: the FNV-1a hash of
+// value modulo count.
+func bucket(value string, count uint64) int64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(value))
+	return int64(hash.Sum64() % count)
+}
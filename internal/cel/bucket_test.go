@@ -0,0 +1,90 @@
+package cel
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestBucketFunctions(t *testing.T) {
+	// The expected buckets are pinned: changing the hash would move
+	// PipelineRuns between the two sides of running rollouts.
+	tests := []struct {
+		name       string
+		expression string
+		expected   string
+		errMsg     string
+	}{
+		{
+			name:       "bucket",
+			expression: `string(bucket("konflux-ci/build-service", 10))`,
+			expected:   "8",
+		},
+		{
+			name:       "single bucket",
+			expression: `string(bucket("konflux-ci/build-service", 1))`,
+			expected:   "0",
+		},
+		{
+			name:       "percent",
+			expression: `string(percent("tenant-a"))`,
+			expected:   "3",
+		},
+		{
+			name:       "percent of another value",
+			expression: `string(percent("tenant-b"))`,
+			expected:   "14",
+		},
+		{
+			name:       "percent of an empty string",
+			expression: `string(percent(""))`,
+			expected:   "37",
+		},
+		{
+			name:       "canary condition",
+			expression: `percent(plrNamespace) < 20 ? "canary" : "stable"`,
+			expected:   "canary",
+		},
+		{
+			name:       "zero buckets",
+			expression: `string(bucket("tenant-a", 0))`,
+			errMsg:     "bucket count must be positive, got 0",
+		},
+		{
+			name:       "negative buckets",
+			expression: `string(bucket("tenant-a", -2))`,
+			errMsg:     "bucket count must be positive, got -2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileCELPrograms([]string{`label("bucket", ` + tt.expression + `)`})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			mutations, err := programs[0].Evaluate(newRulesPipelineRun("tenant-a"))
+			if tt.errMsg != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(mutations).To(HaveLen(1))
+			g.Expect(mutations[0].Value).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestBucket_Distribution(t *testing.T) {
+	g := NewWithT(t)
+
+	counts := make([]int, 4)
+	for i := range 4000 {
+		counts[bucket(fmt.Sprintf("tenant-%d", i), 4)]++
+	}
+	for _, count := range counts {
+		g.Expect(count).To(BeNumerically("~", 1000, 200))
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/common/types"
 	"github.com/google/cel-go/common/types/ref"
+	"github.com/konflux-ci/tekton-kueue/pkg/common"
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/validation"
 )
 
@@ -14,31 +17,10 @@ import (
 // The main constraint is the size limit
 const maxAnnotationValueSize = 256 * 1024 // 256KB
 
-// CompileCELPrograms compiles a list of CEL expressions into type-safe programs
+// CompileCELPrograms compiles a list of CEL expressions into type-safe programs.
+// Each expression is compiled as an anonymous rule, see CompileConfig.
 func CompileCELPrograms(expressions []string) ([]*CompiledProgram, error) {
-	if len(expressions) == 0 {
-		return nil, fmt.Errorf("expressions list cannot be empty")
-	}
-
-	env, err := createCELEnvironment()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
-	}
-
-	programs := make([]*CompiledProgram, 0, len(expressions))
-	for i, expr := range expressions {
-		if expr == "" {
-			return nil, fmt.Errorf("expression %d cannot be empty", i)
-		}
-
-		program, err := compileSingleExpression(env, expr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to compile expression %d (%q): %w", i, expr, err)
-		}
-		programs = append(programs, program)
-	}
-
-	return programs, nil
+	return CompileConfig(config.CEL{Expressions: expressions})
 }
 
 // createCELEnvironment sets up a type-safe CEL environment with PipelineRun context
@@ -47,23 +29,57 @@ func createCELEnvironment() (*cel.Env, error) {
 	mutationRequestType := cel.MapType(cel.StringType, cel.AnyType)
 
 	// Create CEL environment with proper type declarations
-	env, err := cel.NewEnv(
-
-		cel.Variable("pipelineRun", cel.MapType(cel.StringType, cel.AnyType)),
+	opts := []cel.EnvOption{
+		// The PipelineRun in its JSON form, type checked against the Tekton
+		// schema so that unknown fields are rejected at compile time
+		withSchema(pipelineRunSchema),
+		cel.Variable("pipelineRun", pipelineRunSchema.root),
 		cel.Variable("plrNamespace", cel.StringType),
 		cel.Variable("pacEventType", cel.StringType),
 		cel.Variable("pacTestEventType", cel.StringType),
+		// Well-known Pipelines as Code and Konflux metadata, type checked
+		// against their fields, see metadata.go
+		withSchema(metadataSchema),
+		cel.Variable("pac", cel.ObjectType(pacTypeName)),
+		cel.Variable("konflux", cel.ObjectType(konfluxTypeName)),
+		// "namespace" is a reserved word in CEL, hence the Kubernetes
+		// ValidatingAdmissionPolicy style name
+		cel.Variable("namespaceObject", cel.MapType(cel.StringType, cel.MapType(cel.StringType, cel.StringType))),
+		// The admission time, fixed for all rules of a PipelineRun
+		cel.Variable("now", cel.TimestampType),
+		// Hidden variable used by helper functions that inspect the PipelineRun
+		cel.Variable(contextVariable, cel.DynType),
 		// Add type-safe functions for creating MutationRequests
 		createMutationFunction("annotation", MutationTypeAnnotation, mutationRequestType),
 		createMutationFunction("label", MutationTypeLabel, mutationRequestType),
-		createResourceMutationFunction("resource", MutationTypeResource, mutationRequestType),
+		createResourceMutationFunction("resource", "", mutationRequestType),
+		createResourceMutationFunction("resourceSum", config.ResourceAggregationSum, mutationRequestType),
+		createResourceMutationFunction("resourceMax", config.ResourceAggregationMax, mutationRequestType),
+		createResourceMutationFunction("resourceMin", config.ResourceAggregationMin, mutationRequestType),
+		createResourceMutationFunction("resourceSet", config.ResourceAggregationSet, mutationRequestType),
 		createPriorityMutationFunction("priority", mutationRequestType),
+		createQueueMutationFunction("queue", mutationRequestType),
+		createRemovalMutationFunction("removeLabel", MutationTypeRemoveLabel, mutationRequestType),
+		createRemovalMutationFunction("removeAnnotation", MutationTypeRemoveAnnotation, mutationRequestType),
 		// Add string manipulation functions
 		createReplaceFunction("replace"),
-
-		// Enable standard library functions
-		cel.StdLib(),
-	)
+	}
+	// Add regex and string helpers
+	opts = append(opts, createStringFunctions()...)
+	// Add PipelineRun parameter helpers
+	opts = append(opts, createParamFunctions()...)
+	// Add pipeline task helpers
+	opts = append(opts, createTaskFunctions()...)
+	// Add pipelineRef resolver helpers
+	opts = append(opts, createResolverFunctions()...)
+	// Add time window helpers
+	opts = append(opts, createTimeFunctions()...)
+	// Add stable bucketing helpers
+	opts = append(opts, createBucketFunctions()...)
+	// Enable standard library functions
+	opts = append(opts, cel.StdLib())
+
+	env, err := cel.NewEnv(opts...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create type-safe CEL environment: %w", err)
@@ -111,6 +127,11 @@ func createMutationFunction(name string, mutationType MutationType, returnType *
 					err = validateAnnotationValue(value)
 				case MutationTypeLabel:
 					err = validateLabelValue(value)
+					// label() of the queue label must not bypass the
+					// queue naming rules enforced by queue()
+					if err == nil && key == common.QueueLabel {
+						err = ValidateQueueName(value)
+					}
 				}
 
 				if err != nil {
@@ -130,8 +151,53 @@ func createMutationFunction(name string, mutationType MutationType, returnType *
 	)
 }
 
-// createResourceMutationFunction creates a CEL function for resource mutations that accepts string key and int value
-func createResourceMutationFunction(name string, mutationType MutationType, returnType *cel.Type) cel.EnvOption {
+// createRemovalMutationFunction creates a CEL function that removes a label or annotation
+// by key. Removing a key that is not set is a no-op.
+func createRemovalMutationFunction(name string, mutationType MutationType, returnType *cel.Type) cel.EnvOption {
+	return cel.Function(
+		name,
+		cel.Overload(
+			name+"_string_to_mutation",
+			[]*cel.Type{cel.StringType},
+			returnType,
+			cel.UnaryBinding(func(arg ref.Val) ref.Val {
+				key, ok := arg.Value().(string)
+				if !ok {
+					return types.NewErr("%s function requires a string argument", name)
+				}
+
+				if key == "" {
+					return types.NewErr("%s key cannot be empty", name)
+				}
+
+				var err error
+				switch mutationType {
+				case MutationTypeRemoveLabel:
+					err = validateKey(key, "label")
+				case MutationTypeRemoveAnnotation:
+					err = validateKey(key, "annotation")
+				}
+				if err != nil {
+					return types.NewErr("%s key validation failed: %v", name, err)
+				}
+
+				mutationMap := map[string]interface{}{
+					"type":  string(mutationType),
+					"key":   key,
+					"value": "",
+				}
+
+				return types.NewStringInterfaceMap(types.DefaultTypeAdapter, mutationMap)
+			}),
+		),
+	)
+}
+
+// createResourceMutationFunction creates a CEL function for resource mutations. The value is
+// either a non-negative int, for counters such as VM slots, or a Kubernetes quantity string,
+// such as "500m" or "4Gi", for resources like CPU and memory. A non-empty aggregation overrides
+// the configured aggregation of the resource for the mutations created by the function.
+func createResourceMutationFunction(name string, aggregation config.ResourceAggregation, returnType *cel.Type) cel.EnvOption {
 	return cel.Function(
 		name,
 		cel.Overload(
@@ -145,10 +211,6 @@ func createResourceMutationFunction(name string, mutationType MutationType, retu
 					return types.NewErr("%s function requires string key argument", name)
 				}
 
-				if key == "" {
-					return types.NewErr("%s key cannot be empty", name)
-				}
-
 				intValue, intValueOk := rhs.Value().(int64)
 
 				if !intValueOk {
@@ -160,35 +222,73 @@ func createResourceMutationFunction(name string, mutationType MutationType, retu
 					return types.NewErr("%s value must be positive (>= 0), got %d", name, intValue)
 				}
 
-				// Convert int value to string for storage
-				value := fmt.Sprintf("%d", intValue)
+				return newResourceMutation(name, aggregation, key, *resource.NewQuantity(intValue, resource.DecimalSI))
+			}),
+		),
+		cel.Overload(
+			name+"_string_string_to_mutation",
+			[]*cel.Type{cel.StringType, cel.StringType},
+			returnType,
+			cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+				key, keyOk := lhs.Value().(string)
+				value, valueOk := rhs.Value().(string)
 
-				// Validate key using annotation validation since resource mutations create annotations
-				err := validateKey(key, "resource annotation")
-				if err != nil {
-					return types.NewErr("%s key validation failed: %v", name, err)
+				if !keyOk || !valueOk {
+					return types.NewErr("%s function requires string arguments", name)
 				}
 
-				// Validate the converted value using annotation validation
-				err = validateAnnotationValue(value)
+				quantity, err := resource.ParseQuantity(value)
 				if err != nil {
-					return types.NewErr("%s value validation failed: %v", name, err)
+					return types.NewErr("%s value %q is not a valid quantity: %v", name, value, err)
 				}
 
-				// Create strongly-typed MutationRequest structure as map
-				// Note: This mutation type creates annotations but with special summing behavior for duplicates
-				mutationMap := map[string]interface{}{
-					"type":  string(mutationType),
-					"key":   "kueue.konflux-ci.dev/requests-" + key,
-					"value": value,
+				// Validate that the value is positive (non-negative)
+				if quantity.Sign() < 0 {
+					return types.NewErr("%s value must be positive (>= 0), got %s", name, value)
 				}
 
-				return types.NewStringInterfaceMap(types.DefaultTypeAdapter, mutationMap)
+				return newResourceMutation(name, aggregation, key, quantity)
 			}),
 		),
 	)
 }
 
+// newResourceMutation validates the resource key and builds the mutation map shared by
+// both resource() overloads.
+func newResourceMutation(name string, aggregation config.ResourceAggregation, key string, quantity resource.Quantity) ref.Val {
+	if key == "" {
+		return types.NewErr("%s key cannot be empty", name)
+	}
+
+	// Validate key using annotation validation since resource mutations create annotations
+	err := validateKey(key, "resource annotation")
+	if err != nil {
+		return types.NewErr("%s key validation failed: %v", name, err)
+	}
+
+	// Store the quantity in the same form the mutator writes sums in, e.g. "1000" or "4Gi"
+	value := formatQuantity(quantity)
+
+	// Validate the converted value using annotation validation
+	err = validateAnnotationValue(value)
+	if err != nil {
+		return types.NewErr("%s value validation failed: %v", name, err)
+	}
+
+	// Create strongly-typed MutationRequest structure as map
+	// Note: This mutation type creates annotations but with special aggregation behavior for duplicates
+	mutationMap := map[string]interface{}{
+		"type":  string(MutationTypeResource),
+		"key":   resourceAnnotationPrefix + key,
+		"value": value,
+	}
+	if aggregation != "" {
+		mutationMap["aggregation"] = string(aggregation)
+	}
+
+	return types.NewStringInterfaceMap(types.DefaultTypeAdapter, mutationMap)
+}
+
 // createPriorityMutationFunction creates a CEL function for priority mutations with hardcoded key
 func createPriorityMutationFunction(name string, returnType *cel.Type) cel.EnvOption {
 	return cel.Function(
@@ -207,7 +307,40 @@ func createPriorityMutationFunction(name string, returnType *cel.Type) cel.EnvOp
 				// Create strongly-typed MutationRequest structure as map with hardcoded key
 				mutationMap := map[string]interface{}{
 					"type":  string(MutationTypeLabel),
-					"key":   "kueue.x-k8s.io/priority-class",
+					"key":   common.PriorityClassLabel,
+					"value": value,
+				}
+
+				return types.NewStringInterfaceMap(types.DefaultTypeAdapter, mutationMap)
+			}),
+		),
+	)
+}
+
+// createQueueMutationFunction creates a CEL function that routes the PipelineRun
+// to a Kueue LocalQueue. The result takes precedence over both the configured
+// queueName and a queue label supplied by the user.
+func createQueueMutationFunction(name string, returnType *cel.Type) cel.EnvOption {
+	return cel.Function(
+		name,
+		cel.Overload(
+			name+"_string_to_mutation",
+			[]*cel.Type{cel.StringType},
+			returnType,
+			cel.UnaryBinding(func(val ref.Val) ref.Val {
+				value, valueOk := val.Value().(string)
+
+				if !valueOk {
+					return types.NewErr("%s function requires string argument", name)
+				}
+
+				if err := ValidateQueueName(value); err != nil {
+					return types.NewErr("%s value validation failed: %v", name, err)
+				}
+
+				mutationMap := map[string]interface{}{
+					"type":  string(MutationTypeQueue),
+					"key":   common.QueueLabel,
 					"value": value,
 				}
 
@@ -267,8 +400,9 @@ func validateExpressionReturnType(ast *cel.Ast) error {
 	return nil
 }
 
-// compileSingleExpression compiles a single CEL expression with comprehensive type checking
-func compileSingleExpression(env *cel.Env, expression string) (*CompiledProgram, error) {
+// compileSingleExpression compiles a single CEL expression with comprehensive type checking.
+// A non-zero costLimit rejects expressions over budget and is enforced at runtime.
+func compileSingleExpression(env *cel.Env, expression string, costLimit uint64) (*CompiledProgram, error) {
 	// Parse the expression with type checking
 	ast, issues := env.Compile(expression)
 	if issues != nil && issues.Err() != nil {
@@ -280,8 +414,13 @@ func compileSingleExpression(env *cel.Env, expression string) (*CompiledProgram,
 		return nil, fmt.Errorf("invalid return type for expression %q: %w", expression, err)
 	}
 
+	// Reject expressions whose worst case is over the cost budget
+	if err := checkCost(env, ast, costLimit); err != nil {
+		return nil, fmt.Errorf("cost check failed for expression %q: %w", expression, err)
+	}
+
 	// Create the program
-	program, err := env.Program(ast)
+	program, err := env.Program(ast, programOptions(costLimit)...)
 	if err != nil {
 		return nil, fmt.Errorf("program creation failed for expression %q: %w", expression, err)
 	}
@@ -290,6 +429,7 @@ func compileSingleExpression(env *cel.Env, expression string) (*CompiledProgram,
 		program:    program,
 		ast:        ast,
 		expression: expression,
+		onError:    config.OnErrorFail,
 	}, nil
 }
 
@@ -319,6 +459,20 @@ func validateLabelValue(value string) error {
 	return nil
 }
 
+// ValidateQueueName validates that a value is usable as a LocalQueue name.
+// The name must be a valid object name and, since it is stored in the
+// queue-name label, a valid label value. It is shared by queue() and the
+// queueRouting of the configuration, so both accept the same names.
+func ValidateQueueName(value string) error {
+	if value == "" {
+		return fmt.Errorf("queue name cannot be empty")
+	}
+	if errs := validation.IsDNS1123Subdomain(value); len(errs) > 0 {
+		return fmt.Errorf("queue name '%s' is invalid: %s", value, strings.Join(errs, ", "))
+	}
+	return validateLabelValue(value)
+}
+
 // validateAnnotationValue validates that an annotation value conforms to Kubernetes constraints
 func validateAnnotationValue(value string) error {
 	if len(value) > maxAnnotationValueSize {
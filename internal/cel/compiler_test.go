@@ -50,7 +50,7 @@ func TestCompileCELPrograms_TypeSafety(t *testing.T) {
 				"", // empty expression
 			},
 			expectErr: true,
-			errMsg:    "expression 1 cannot be empty",
+			errMsg:    "cel.expressions[1]: expression cannot be empty",
 		},
 		{
 			name: "invalid function",
@@ -83,7 +83,7 @@ func TestCompileCELPrograms_TypeSafety(t *testing.T) {
 		{
 			name: "type error - resource wrong second arg",
 			expressions: []string{
-				`resource("valid-key", "not-an-int")`, // second argument should be int
+				`resource("valid-key", true)`, // second argument should be int or quantity string
 			},
 			expectErr: true,
 		},
@@ -602,6 +602,42 @@ func TestResourceFunction_ValidCases(t *testing.T) {
 				"value": "2000",
 			},
 		},
+		{
+			name:       "valid resource with milli cpu quantity",
+			expression: `resource("cpu", "500m")`,
+			expected: map[string]interface{}{
+				"type":  "resource",
+				"key":   "kueue.konflux-ci.dev/requests-cpu",
+				"value": "500m",
+			},
+		},
+		{
+			name:       "valid resource with binary memory quantity",
+			expression: `resource("memory", "4Gi")`,
+			expected: map[string]interface{}{
+				"type":  "resource",
+				"key":   "kueue.konflux-ci.dev/requests-memory",
+				"value": "4Gi",
+			},
+		},
+		{
+			name:       "valid resource with integer quantity string",
+			expression: `resource("aws-vm-x", "2")`,
+			expected: map[string]interface{}{
+				"type":  "resource",
+				"key":   "kueue.konflux-ci.dev/requests-aws-vm-x",
+				"value": "2",
+			},
+		},
+		{
+			name:       "valid resource with large int keeps integer form",
+			expression: `resource("aws-vm-x", 1000000)`,
+			expected: map[string]interface{}{
+				"type":  "resource",
+				"key":   "kueue.konflux-ci.dev/requests-aws-vm-x",
+				"value": "1000000",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -647,6 +683,21 @@ func TestResourceFunction_ErrorCases(t *testing.T) {
 			expression: `resource("aws-vm-x", -500)`,
 			errorMsg:   "resource value must be positive (>= 0), got -500",
 		},
+		{
+			name:       "invalid resource with negative quantity",
+			expression: `resource("cpu", "-500m")`,
+			errorMsg:   "resource value must be positive (>= 0), got -500m",
+		},
+		{
+			name:       "invalid resource with malformed quantity",
+			expression: `resource("memory", "4GB")`,
+			errorMsg:   `resource value "4GB" is not a valid quantity`,
+		},
+		{
+			name:       "invalid resource with empty key and quantity",
+			expression: `resource("", "1Gi")`,
+			errorMsg:   "resource key cannot be empty",
+		},
 		{
 			name:       "invalid resource with empty key",
 			expression: `resource("", 100)`,
@@ -728,3 +779,152 @@ func TestResourceFunctionIntegration(t *testing.T) {
 	g.Expect(err).NotTo(HaveOccurred(), "All expressions should compile successfully")
 	g.Expect(programs).To(HaveLen(3), "Should have compiled 3 programs")
 }
+
+func TestQueueFunction(t *testing.T) {
+	g := NewWithT(t)
+
+	// Create a CEL environment for testing
+	env, err := createCELEnvironment()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   map[string]interface{}
+		errorMsg   string
+	}{
+		{
+			name:       "valid queue name",
+			expression: `queue("release-queue")`,
+			expected: map[string]interface{}{
+				"type":  "queue",
+				"key":   "kueue.x-k8s.io/queue-name",
+				"value": "release-queue",
+			},
+		},
+		{
+			name:       "valid queue name with dots",
+			expression: `queue("nightly.builds")`,
+			expected: map[string]interface{}{
+				"type":  "queue",
+				"key":   "kueue.x-k8s.io/queue-name",
+				"value": "nightly.builds",
+			},
+		},
+		{
+			name:       "empty queue name",
+			expression: `queue("")`,
+			errorMsg:   "queue name cannot be empty",
+		},
+		{
+			name:       "uppercase queue name",
+			expression: `queue("Release")`,
+			errorMsg:   "queue value validation failed",
+		},
+		{
+			name:       "queue name longer than a label value",
+			expression: `queue("` + strings.Repeat("a", maxLabelValueLength+1) + `")`,
+			errorMsg:   "queue value validation failed",
+		},
+		{
+			name:       "valid queue name set with label",
+			expression: `label("kueue.x-k8s.io/queue-name", "release-queue")`,
+			expected: map[string]interface{}{
+				"type":  "label",
+				"key":   "kueue.x-k8s.io/queue-name",
+				"value": "release-queue",
+			},
+		},
+		{
+			name:       "invalid queue name set with label",
+			expression: `label("kueue.x-k8s.io/queue-name", "Not_Valid")`,
+			errorMsg:   "label value validation failed: queue name 'Not_Valid' is invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ast, issues := env.Compile(tt.expression)
+			g.Expect(issues.Err()).NotTo(HaveOccurred(), "Expression should compile successfully")
+
+			program, err := env.Program(ast)
+			g.Expect(err).NotTo(HaveOccurred(), "Program creation should succeed")
+
+			result, _, err := program.Eval(map[string]interface{}{})
+			if tt.errorMsg != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errorMsg)))
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(result.Value()).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestRemovalFunctions(t *testing.T) {
+	g := NewWithT(t)
+
+	// Create a CEL environment for testing
+	env, err := createCELEnvironment()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   map[string]interface{}
+		errorMsg   string
+	}{
+		{
+			name:       "remove label",
+			expression: `removeLabel("kueue.x-k8s.io/priority-class")`,
+			expected: map[string]interface{}{
+				"type":  "removeLabel",
+				"key":   "kueue.x-k8s.io/priority-class",
+				"value": "",
+			},
+		},
+		{
+			name:       "remove annotation",
+			expression: `removeAnnotation("kueue.konflux-ci.dev/requests-aws-vm-x")`,
+			expected: map[string]interface{}{
+				"type":  "removeAnnotation",
+				"key":   "kueue.konflux-ci.dev/requests-aws-vm-x",
+				"value": "",
+			},
+		},
+		{
+			name:       "empty label key",
+			expression: `removeLabel("")`,
+			errorMsg:   "removeLabel key cannot be empty",
+		},
+		{
+			name:       "invalid annotation key",
+			expression: `removeAnnotation("-invalid")`,
+			errorMsg:   "removeAnnotation key validation failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ast, issues := env.Compile(tt.expression)
+			g.Expect(issues.Err()).NotTo(HaveOccurred(), "Expression should compile successfully")
+
+			program, err := env.Program(ast)
+			g.Expect(err).NotTo(HaveOccurred(), "Program creation should succeed")
+
+			result, _, err := program.Eval(map[string]interface{}{})
+			if tt.errorMsg != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errorMsg)))
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(result.Value()).To(Equal(tt.expected))
+		})
+	}
+}
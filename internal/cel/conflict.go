@@ -0,0 +1,154 @@
+package cel
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/konflux-ci/tekton-kueue/pkg/common"
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// validConflictPolicies returns all supported conflict policies
+func validConflictPolicies() []config.ConflictPolicy {
+	return []config.ConflictPolicy{
+		config.ConflictPolicyLastWins, config.ConflictPolicyFirstWins,
+		config.ConflictPolicyError, config.ConflictPolicyHighestPriority,
+	}
+}
+
+// validateConflictPolicy checks the conflict policy and the priority classes
+// it depends on. An empty policy selects lastWins.
+func validateConflictPolicy(cfg config.CEL) error {
+	if cfg.ConflictPolicy != "" && !slices.Contains(validConflictPolicies(), cfg.ConflictPolicy) {
+		return fmt.Errorf("cel.conflictPolicy: invalid conflictPolicy %q, must be one of: %v", cfg.ConflictPolicy, validConflictPolicies())
+	}
+	if cfg.ConflictPolicy == config.ConflictPolicyHighestPriority && len(cfg.PriorityClasses) == 0 {
+		return fmt.Errorf("cel.priorityClasses: conflictPolicy %q requires priorityClasses", cfg.ConflictPolicy)
+	}
+	seen := make(map[string]bool, len(cfg.PriorityClasses))
+	for i, class := range cfg.PriorityClasses {
+		if class == "" {
+			return fmt.Errorf("cel.priorityClasses[%d]: priorityClasses cannot contain an empty name", i)
+		}
+		if seen[class] {
+			return fmt.Errorf("cel.priorityClasses[%d]: duplicate priority class %q", i, class)
+		}
+		seen[class] = true
+	}
+	return nil
+}
+
+// conflictTarget identifies the label or annotation written by a mutation.
+type conflictTarget struct {
+	label bool
+	key   string
+}
+
+func (t conflictTarget) String() string {
+	if t.label {
+		return fmt.Sprintf("label %q", t.key)
+	}
+	return fmt.Sprintf("annotation %q", t.key)
+}
+
+// targetOf returns the label or annotation written by a mutation. Resource
+// mutations have no target because they are summed instead of overwritten.
+func targetOf(mutation *MutationRequest) (conflictTarget, bool) {
+	switch mutation.Type {
+	case MutationTypeLabel, MutationTypeQueue, MutationTypeRemoveLabel:
+		return conflictTarget{label: true, key: mutation.Key}, true
+	case MutationTypeAnnotation, MutationTypeRemoveAnnotation:
+		return conflictTarget{label: false, key: mutation.Key}, true
+	default:
+		return conflictTarget{}, false
+	}
+}
+
+// describeMutation renders the effect of a mutation on its target for conflict messages.
+func describeMutation(rm ruleMutation) string {
+	if rm.mutation.Type.IsRemoval() {
+		return fmt.Sprintf("rule %q removes it", rm.rule)
+	}
+	return fmt.Sprintf("rule %q sets %q", rm.rule, rm.mutation.Value)
+}
+
+// resolveConflicts drops the mutations that lose a conflict under the
+// configured policy. Two mutations conflict when they write the same label
+// or annotation with different results; identical mutations do not
+// conflict. The remaining mutations keep their evaluation order.
+//
+// Parameters:
+//   - ctx: The context of the admission request, carrying the logger
+//   - mutations: All mutations in evaluation order
+//
+// Returns:
+//   - []ruleMutation: The mutations to apply
+//   - error: A conflict under the error policy
+func (m *CELMutator) resolveConflicts(ctx context.Context, mutations []ruleMutation) ([]ruleMutation, error) {
+	log := logf.FromContext(ctx)
+
+	policy := m.conflictPolicy
+	if policy == "" {
+		policy = config.ConflictPolicyLastWins
+	}
+
+	// winners maps every target to the index of the mutation applied to it
+	winners := map[conflictTarget]int{}
+	for i, rm := range mutations {
+		target, ok := targetOf(rm.mutation)
+		if !ok {
+			continue
+		}
+		winner, exists := winners[target]
+		if !exists {
+			winners[target] = i
+			continue
+		}
+		previous := mutations[winner]
+		if previous.mutation.Type.IsRemoval() == rm.mutation.Type.IsRemoval() && previous.mutation.Value == rm.mutation.Value {
+			continue
+		}
+
+		RecordConflict(string(policy))
+		log.Info("Conflicting CEL mutations", "target", target.String(),
+			"rule", previous.rule, "conflictingRule", rm.rule, "policy", policy)
+
+		switch policy {
+		case config.ConflictPolicyFirstWins:
+		case config.ConflictPolicyError:
+			return nil, &EvaluationError{Err: fmt.Errorf("conflicting mutations of %s: %s, %s",
+				target, describeMutation(previous), describeMutation(rm))}
+		case config.ConflictPolicyHighestPriority:
+			if !target.label || target.key != common.PriorityClassLabel ||
+				m.priorityRank(rm.mutation) <= m.priorityRank(previous.mutation) {
+				winners[target] = i
+			}
+		default:
+			winners[target] = i
+		}
+	}
+
+	resolved := make([]ruleMutation, 0, len(mutations))
+	for i, rm := range mutations {
+		if target, ok := targetOf(rm.mutation); ok && winners[target] != i {
+			continue
+		}
+		resolved = append(resolved, rm)
+	}
+	return resolved, nil
+}
+
+// priorityRank returns the position of the priority class set by a mutation
+// in the configured priority classes, lower being higher priority. Removals
+// and unlisted classes rank below all listed classes.
+func (m *CELMutator) priorityRank(mutation *MutationRequest) int {
+	if mutation.Type.IsRemoval() {
+		return len(m.priorityClasses)
+	}
+	if rank := slices.Index(m.priorityClasses, mutation.Value); rank >= 0 {
+		return rank
+	}
+	return len(m.priorityClasses)
+}
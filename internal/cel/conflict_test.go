@@ -0,0 +1,182 @@
+package cel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	. "github.com/onsi/gomega"
+)
+
+func TestCELMutator_Mutate_ConflictPolicy(t *testing.T) {
+	rules := []config.Rule{
+		{Name: "team-a", Mutations: `[priority("konflux-low"), annotation("owner", "team-a"), label("env", "test")]`},
+		{Name: "team-b", Mutations: `[priority("konflux-high"), annotation("owner", "team-b"), label("env", "test")]`},
+		{Name: "team-c", Mutations: `[priority("konflux-medium"), removeAnnotation("owner")]`},
+	}
+
+	tests := []struct {
+		name                string
+		policy              config.ConflictPolicy
+		expectedLabels      map[string]string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:                "default is lastWins",
+			policy:              "",
+			expectedLabels:      map[string]string{"kueue.x-k8s.io/priority-class": "konflux-medium", "env": "test"},
+			expectedAnnotations: nil,
+		},
+		{
+			name:                "lastWins",
+			policy:              config.ConflictPolicyLastWins,
+			expectedLabels:      map[string]string{"kueue.x-k8s.io/priority-class": "konflux-medium", "env": "test"},
+			expectedAnnotations: nil,
+		},
+		{
+			name:                "firstWins",
+			policy:              config.ConflictPolicyFirstWins,
+			expectedLabels:      map[string]string{"kueue.x-k8s.io/priority-class": "konflux-low", "env": "test"},
+			expectedAnnotations: map[string]string{"owner": "team-a"},
+		},
+		{
+			name:                "highestPriority",
+			policy:              config.ConflictPolicyHighestPriority,
+			expectedLabels:      map[string]string{"kueue.x-k8s.io/priority-class": "konflux-high", "env": "test"},
+			expectedAnnotations: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileConfig(config.CEL{Rules: rules})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			mutator := NewCELMutator(programs, WithConflictPolicy(tt.policy, []string{"konflux-high", "konflux-medium", "konflux-low"}))
+			pipelineRun := newRulesPipelineRun("tenant")
+			err = mutator.Mutate(context.Background(), pipelineRun, Inputs{})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(pipelineRun.Labels).To(Equal(tt.expectedLabels))
+			g.Expect(pipelineRun.Annotations).To(Equal(tt.expectedAnnotations))
+		})
+	}
+}
+
+func TestCELMutator_Mutate_ConflictPolicyError(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []config.Rule
+		errMsg string
+	}{
+		{
+			name: "different values",
+			rules: []config.Rule{
+				{Name: "team-a", Mutations: `priority("konflux-low")`},
+				{Name: "team-b", Mutations: `priority("konflux-high")`},
+			},
+			errMsg: `conflicting mutations of label "kueue.x-k8s.io/priority-class": rule "team-a" sets "konflux-low", rule "team-b" sets "konflux-high"`,
+		},
+		{
+			name: "set and removal",
+			rules: []config.Rule{
+				{Name: "team-a", Mutations: `annotation("owner", "team-a")`},
+				{Name: "team-b", Mutations: `removeAnnotation("owner")`},
+			},
+			errMsg: `conflicting mutations of annotation "owner": rule "team-a" sets "team-a", rule "team-b" removes it`,
+		},
+		{
+			name: "queue and label of the queue key",
+			rules: []config.Rule{
+				{Name: "team-a", Mutations: `queue("team-a-queue")`},
+				{Name: "team-b", Mutations: `label("kueue.x-k8s.io/queue-name", "team-b-queue")`},
+			},
+			errMsg: `conflicting mutations of label "kueue.x-k8s.io/queue-name"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileConfig(config.CEL{Rules: tt.rules})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			mutator := NewCELMutator(programs, WithConflictPolicy(config.ConflictPolicyError, nil))
+			pipelineRun := newRulesPipelineRun("tenant")
+			err = mutator.Mutate(context.Background(), pipelineRun, Inputs{})
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+
+			var evaluationErr *EvaluationError
+			g.Expect(err).To(BeAssignableToTypeOf(evaluationErr))
+			g.Expect(pipelineRun.Labels).To(BeNil())
+			g.Expect(pipelineRun.Annotations).To(BeNil())
+		})
+	}
+}
+
+func TestCELMutator_Mutate_ConflictPolicyErrorWithoutConflict(t *testing.T) {
+	g := NewWithT(t)
+
+	programs, err := CompileConfig(config.CEL{Rules: []config.Rule{
+		{Name: "team-a", Mutations: `[label("env", "test"), resource("cpu", 1)]`},
+		{Name: "team-b", Mutations: `[label("env", "test"), resource("cpu", 2)]`},
+	}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	mutator := NewCELMutator(programs, WithConflictPolicy(config.ConflictPolicyError, nil))
+	pipelineRun := newRulesPipelineRun("tenant")
+	err = mutator.Mutate(context.Background(), pipelineRun, Inputs{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pipelineRun.Labels).To(Equal(map[string]string{"env": "test"}))
+	g.Expect(pipelineRun.Annotations).To(Equal(map[string]string{"kueue.konflux-ci.dev/requests-cpu": "3"}))
+}
+
+func TestCompileConfig_ConflictPolicyErrors(t *testing.T) {
+	expressions := []string{`label("env", "test")`}
+
+	tests := []struct {
+		name   string
+		cfg    config.CEL
+		errMsg string
+	}{
+		{
+			name:   "unknown policy",
+			cfg:    config.CEL{Expressions: expressions, ConflictPolicy: "random"},
+			errMsg: `invalid conflictPolicy "random"`,
+		},
+		{
+			name:   "highestPriority without priority classes",
+			cfg:    config.CEL{Expressions: expressions, ConflictPolicy: config.ConflictPolicyHighestPriority},
+			errMsg: `conflictPolicy "highestPriority" requires priorityClasses`,
+		},
+		{
+			name: "duplicate priority class",
+			cfg: config.CEL{
+				Expressions:     expressions,
+				ConflictPolicy:  config.ConflictPolicyHighestPriority,
+				PriorityClasses: []string{"konflux-high", "konflux-high"},
+			},
+			errMsg: `duplicate priority class "konflux-high"`,
+		},
+		{
+			name: "empty priority class",
+			cfg: config.CEL{
+				Expressions:     expressions,
+				ConflictPolicy:  config.ConflictPolicyHighestPriority,
+				PriorityClasses: []string{""},
+			},
+			errMsg: "priorityClasses cannot contain an empty name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := CompileConfig(tt.cfg)
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+		})
+	}
+}
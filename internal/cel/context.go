@@ -0,0 +1,98 @@
+package cel
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+)
+
+// contextVariable is the name of the hidden CEL variable that carries the
+// typed PipelineRun into helper functions such as param(). Users never
+// reference it directly: the helper macros rewrite param("x") into
+// param(__context__, "x") at parse time.
+const contextVariable = "__context__"
+
+// contextType is the CEL type reported by evaluationContext values.
+var contextType = types.NewOpaqueType("tektonkueue.Context")
+
+// evaluationContext wraps the typed PipelineRun so that helper functions can
+// inspect it without going through the untyped pipelineRun map. It is built
+// once per evaluation and shared by all programs.
+type evaluationContext struct {
+	pipelineRun *tekv1.PipelineRun
+	params      map[string]tekv1.ParamValue
+	// now is the evaluation time, also exposed as the now variable
+	now time.Time
+}
+
+// newEvaluationContext creates the context for a single evaluation.
+func newEvaluationContext(pipelineRun *tekv1.PipelineRun, now time.Time) *evaluationContext {
+	return &evaluationContext{
+		pipelineRun: pipelineRun,
+		params:      effectiveParams(pipelineRun),
+		now:         now,
+	}
+}
+
+// ConvertToNative implements ref.Val.
+func (c *evaluationContext) ConvertToNative(typeDesc reflect.Type) (any, error) {
+	return nil, fmt.Errorf("type conversion error from '%s' to '%v'", contextType, typeDesc)
+}
+
+// ConvertToType implements ref.Val.
+func (c *evaluationContext) ConvertToType(typeVal ref.Type) ref.Val {
+	if typeVal == types.TypeType {
+		return contextType
+	}
+	return types.NewErr("type conversion error from '%s' to '%s'", contextType, typeVal)
+}
+
+// Equal implements ref.Val.
+func (c *evaluationContext) Equal(other ref.Val) ref.Val {
+	return types.Bool(c == other)
+}
+
+// Type implements ref.Val.
+func (c *evaluationContext) Type() ref.Type {
+	return contextType
+}
+
+// Value implements ref.Val.
+func (c *evaluationContext) Value() any {
+	return c
+}
+
+// contextFromVal extracts the evaluation context from the hidden argument
+// injected by the helper macros.
+func contextFromVal(val ref.Val) (*evaluationContext, bool) {
+	c, ok := val.(*evaluationContext)
+	return c, ok
+}
+
+// createContextMacro creates a global macro that rewrites a call to function
+// with argCount arguments into the same function with the hidden context
+// variable prepended. This is how helpers gain access to the PipelineRun
+// being evaluated without the user having to pass it explicitly.
+func createContextMacro(function string, argCount int) cel.EnvOption {
+	return cel.Macros(cel.GlobalMacro(function, argCount,
+		func(eh cel.MacroExprFactory, _ ast.Expr, args []ast.Expr) (ast.Expr, *common.Error) {
+			return newContextCall(eh, function, args), nil
+		},
+	))
+}
+
+// newContextCall returns a call to function with the hidden context variable
+// prepended to args.
+func newContextCall(eh cel.MacroExprFactory, function string, args []ast.Expr) ast.Expr {
+	callArgs := make([]ast.Expr, 0, len(args)+1)
+	callArgs = append(callArgs, eh.NewIdent(contextVariable))
+	callArgs = append(callArgs, args...)
+	return eh.NewCall(function, callArgs...)
+}
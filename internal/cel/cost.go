@@ -0,0 +1,112 @@
+package cel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/interpreter"
+)
+
+const (
+	// maxRequestSizeBytes bounds the size of any value derived from the
+	// PipelineRun for static cost estimation. It matches the maximum request
+	// body accepted by the Kubernetes API server.
+	maxRequestSizeBytes = 3 * 1024 * 1024
+
+	// minElementSizeBytes is the smallest serialized size of an element of
+	// a list or map, e.g. `"",`, used to bound collection sizes.
+	minElementSizeBytes = 3
+
+	// interruptCheckFrequency is the number of comprehension iterations
+	// between checks of the evaluation deadline, as in Kubernetes.
+	interruptCheckFrequency = 100
+)
+
+// CostLimitError indicates that a CEL rule was stopped because it went over
+// the configured cost budget, or because the evaluation deadline expired.
+type CostLimitError struct {
+	// Timeout is true if the evaluation deadline expired, false if the
+	// cost budget was exceeded.
+	Timeout bool
+	Err     error
+}
+
+func (e *CostLimitError) Error() string {
+	if e.Timeout {
+		return fmt.Sprintf("CEL evaluation timed out: %v", e.Err)
+	}
+	return fmt.Sprintf("CEL cost limit exceeded: %v", e.Err)
+}
+
+func (e *CostLimitError) Unwrap() error {
+	return e.Err
+}
+
+// costEstimator provides worst-case sizes for static cost estimation. The
+// PipelineRun, the variables derived from it and the results of helper
+// functions have no declared size, so they are bounded by the largest
+// object the API server accepts.
+type costEstimator struct{}
+
+// EstimateSize implements checker.CostEstimator.
+func (costEstimator) EstimateSize(element checker.AstNode) *checker.SizeEstimate {
+	switch element.Type().Kind() {
+	case types.StringKind, types.BytesKind:
+		return &checker.SizeEstimate{Min: 0, Max: maxRequestSizeBytes}
+	case types.ListKind, types.MapKind, types.DynKind, types.AnyKind:
+		return &checker.SizeEstimate{Min: 0, Max: maxRequestSizeBytes / minElementSizeBytes}
+	}
+	return nil
+}
+
+// EstimateCallCost implements checker.CostEstimator.
+func (costEstimator) EstimateCallCost(_, _ string, _ *checker.AstNode, _ []checker.AstNode) *checker.CallEstimate {
+	return nil
+}
+
+// programOptions returns the program options enforcing the cost budget at
+// runtime and allowing evaluation to be interrupted by a context deadline.
+func programOptions(costLimit uint64) []cel.ProgramOption {
+	opts := []cel.ProgramOption{cel.InterruptCheckFrequency(interruptCheckFrequency)}
+	if costLimit > 0 {
+		opts = append(opts, cel.CostLimit(costLimit))
+	}
+	return opts
+}
+
+// checkCost rejects an expression whose estimated worst-case cost is over
+// the budget. A zero costLimit disables the check.
+func checkCost(env *cel.Env, ast *cel.Ast, costLimit uint64) error {
+	if costLimit == 0 {
+		return nil
+	}
+	estimate, err := env.EstimateCost(ast, costEstimator{})
+	if err != nil {
+		return fmt.Errorf("failed to estimate cost: %w", err)
+	}
+	if estimate.Max > costLimit {
+		return &CostLimitError{Err: fmt.Errorf("estimated worst-case cost %d is over the cost limit %d", estimate.Max, costLimit)}
+	}
+	return nil
+}
+
+// cancellationError converts an evaluation stopped by the cost budget or by
+// the context into a CostLimitError. It returns nil for any other error.
+func cancellationError(ctx context.Context, err error) *CostLimitError {
+	var cancelled interpreter.EvalCancelledError
+	isCancelled := errors.As(err, &cancelled)
+	if isCancelled && cancelled.Cause == interpreter.CostLimitExceeded {
+		return &CostLimitError{Err: err}
+	}
+	if !isCancelled && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return &CostLimitError{Timeout: true, Err: ctxErr}
+	}
+	return &CostLimitError{Timeout: true, Err: err}
+}
@@ -0,0 +1,181 @@
+package cel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const nestedComprehensionExpression = `paramArray("build-platforms").map(p,
+	paramArray("build-platforms").map(q, label("pair", replace(p + q, "/", "-"))))[0]`
+
+func TestCompileConfig_CostLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.CEL
+		expectErr bool
+	}{
+		{
+			name: "no cost limit",
+			cfg: config.CEL{
+				Expressions: []string{nestedComprehensionExpression},
+			},
+			expectErr: false,
+		},
+		{
+			name: "single comprehension within budget",
+			cfg: config.CEL{
+				Expressions: []string{`paramArray("build-platforms").map(p, resource(replace(p, "/", "-"), 1))`},
+				CostLimit:   100000000,
+			},
+			expectErr: false,
+		},
+		{
+			name: "nested comprehension over budget",
+			cfg: config.CEL{
+				Expressions: []string{nestedComprehensionExpression},
+				CostLimit:   100000000,
+			},
+			expectErr: true,
+		},
+		{
+			name: "when condition over budget",
+			cfg: config.CEL{
+				Rules: []config.Rule{{
+					Name:      "platforms",
+					When:      `paramArray("build-platforms").exists(p, paramArray("build-platforms").exists(q, p != q))`,
+					Mutations: `label("multi-arch", "true")`,
+				}},
+				CostLimit: 100000000,
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := CompileConfig(tt.cfg)
+			if !tt.expectErr {
+				g.Expect(err).NotTo(HaveOccurred())
+				return
+			}
+
+			var costErr *CostLimitError
+			g.Expect(errors.As(err, &costErr)).To(BeTrue(), "expected *CostLimitError, got %v", err)
+			g.Expect(costErr.Timeout).To(BeFalse())
+			g.Expect(err.Error()).To(ContainSubstring("is over the cost limit 100000000"))
+		})
+	}
+}
+
+func TestCompiledProgram_Evaluate_CostLimit(t *testing.T) {
+	g := NewWithT(t)
+
+	env, err := createCELEnvironment()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The runtime budget is normally backed by the static check; compile
+	// without it to exercise the runtime limit alone.
+	expression := `paramArray("build-platforms").map(p, label("platform", replace(p, "/", "-")))`
+	ast, issues := env.Compile(expression)
+	g.Expect(issues.Err()).NotTo(HaveOccurred())
+	program, err := env.Program(ast, programOptions(5)...)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	cp := &CompiledProgram{name: "platforms", program: program, ast: ast, expression: expression}
+	_, err = cp.Evaluate(newParamsPipelineRun())
+
+	var costErr *CostLimitError
+	g.Expect(errors.As(err, &costErr)).To(BeTrue(), "expected *CostLimitError, got %v", err)
+	g.Expect(costErr.Timeout).To(BeFalse())
+
+	var evalErr *EvaluationError
+	g.Expect(errors.As(err, &evalErr)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring(`rule "platforms": CEL cost limit exceeded`))
+}
+
+func TestCompiledProgram_HandleError_FallbackCostLimit(t *testing.T) {
+	g := NewWithT(t)
+
+	env, err := createCELEnvironment()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// As above, compile without the static check to exercise the runtime
+	// limit of the fallback alone.
+	expression := `paramArray("build-platforms").map(p, label("platform", replace(p, "/", "-")))`
+	ast, issues := env.Compile(expression)
+	g.Expect(issues.Err()).NotTo(HaveOccurred())
+	fallback, err := env.Program(ast, programOptions(5)...)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	cp := &CompiledProgram{
+		name:               "platforms-fallback",
+		onError:            config.OnErrorDefault,
+		fallback:           fallback,
+		fallbackExpression: expression,
+	}
+	vars, err := newActivation(newParamsPipelineRun(), Inputs{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	costExceeded := testutil.ToFloat64(celEvaluationsTotal.WithLabelValues("cost_exceeded", "platforms-fallback"))
+	fallbackCostExceeded := testutil.ToFloat64(celFallbacksTotal.WithLabelValues("cost_exceeded", "platforms-fallback"))
+	_, err = cp.handleError(context.Background(), vars, errors.New("rule failed"))
+
+	var costErr *CostLimitError
+	g.Expect(errors.As(err, &costErr)).To(BeTrue(), "expected *CostLimitError, got %v", err)
+	g.Expect(costErr.Timeout).To(BeFalse())
+	g.Expect(err.Error()).To(ContainSubstring(`rule "platforms-fallback": CEL cost limit exceeded: fallback failed`))
+	// The failure of the rule itself was recorded by evaluate
+	g.Expect(testutil.ToFloat64(celEvaluationsTotal.WithLabelValues("cost_exceeded", "platforms-fallback"))).To(Equal(costExceeded))
+	g.Expect(testutil.ToFloat64(celFallbacksTotal.WithLabelValues("cost_exceeded", "platforms-fallback"))).To(Equal(fallbackCostExceeded + 1))
+}
+
+func TestCELMutator_Mutate_Timeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     func() (context.Context, context.CancelFunc)
+		options []MutatorOption
+	}{
+		{
+			name: "evaluation timeout",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.Background(), func() {}
+			},
+			options: []MutatorOption{WithEvaluationTimeout(time.Nanosecond)},
+		},
+		{
+			name: "admission request deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithDeadline(context.Background(), time.Now())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileCELPrograms([]string{`label("env", "test")`})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			pipelineRun := newRulesPipelineRun("test-namespace")
+			err = NewCELMutator(programs, tt.options...).Mutate(ctx, pipelineRun, Inputs{})
+
+			var costErr *CostLimitError
+			g.Expect(errors.As(err, &costErr)).To(BeTrue(), "expected *CostLimitError, got %v", err)
+			g.Expect(costErr.Timeout).To(BeTrue())
+			g.Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+			g.Expect(pipelineRun.Labels).To(BeNil())
+		})
+	}
+}
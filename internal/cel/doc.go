@@ -12,7 +12,7 @@
 //
 //   - Input: *tekton.PipelineRun (strongly typed and validated)
 //   - Output: []MutationRequest (validated structure and content)
-//   - Functions: annotation(key, value), label(key, value), and priority(value)
+//   - Functions: annotation(key, value), label(key, value), priority(value), and queue(name)
 //   - Expressions: Single mutations or lists of mutations
 //
 // # Basic Usage
@@ -39,6 +39,45 @@
 //		// Apply mutations to Kubernetes resources...
 //	}
 //
+// # Named Rules
+//
+// Expressions can also be grouped into named rules with an optional bool guard.
+// The rule name is used in logs, the rule label of metrics and error messages;
+// plain expressions are anonymous rules named "expressions[N]":
+//
+//	programs, err := cel.CompileConfig(config.CEL{
+//		Rules: []config.Rule{{
+//			Name:      "release-priority",
+//			When:      `plrNamespace == "release"`,
+//			Mutations: `priority("konflux-release")`,
+//		}},
+//	})
+//
+// Variables are named expressions shared by all rules, read as variables.<name>.
+// They are compiled in declaration order, each one seeing the variables before
+// it, and evaluated lazily, at most once per PipelineRun:
+//
+//	programs, err := cel.CompileConfig(config.CEL{
+//		Variables: []config.Variable{{
+//			Name:       "isMultiArch",
+//			Expression: `paramArray("build-platforms").size() > 1`,
+//		}},
+//		Rules: []config.Rule{{
+//			Name:      "multi-arch-priority",
+//			When:      `variables.isMultiArch`,
+//			Mutations: `priority("konflux-high")`,
+//		}},
+//	})
+//
+// A named rule may set OnError to keep a failure from rejecting the PipelineRun:
+// "skip" drops the rule's mutations and "default" applies its Fallback
+// expression instead. Failures are still logged and counted. Timeouts always
+// fail, since the evaluation deadline covers all rules.
+//
+// A rule with Shadow set is evaluated after the live rules, but its mutations
+// are never applied. They are compared with the live result instead, and the
+// outcome is logged and counted by whether it matched.
+//
 // # CELMutator Usage
 //
 // For convenient mutation application, use the CELMutator:
@@ -57,7 +96,7 @@
 //	mutator := cel.NewCELMutator(programs)
 //	pipelineRun := &tekton.PipelineRun{...}
 //
-//	err = mutator.Mutate(pipelineRun)
+//	err = mutator.Mutate(ctx, pipelineRun, cel.Inputs{Namespace: namespace})
 //	if err != nil {
 //		log.Printf("Mutation failed: %v", err)
 //	}
@@ -74,15 +113,144 @@
 //   - priority(value: string) -> MutationRequest
 //     Creates a label mutation with key "kueue.x-k8s.io/priority-class" and the specified value
 //
+//   - resource(key: string, value: int | string) -> MutationRequest
+//     Requests value units of a resource through the "kueue.konflux-ci.dev/requests-<key>" annotation.
+//     The value is a non-negative int or a Kubernetes quantity such as "500m" or "4Gi". Requests for
+//     the same key are combined with the aggregation configured for the resource, summing by default.
+//
+//   - resourceSum, resourceMax, resourceMin, resourceSet(key: string, value: int | string) -> MutationRequest
+//     Like resource(), but combine requests for the same key by adding them up, keeping the largest,
+//     keeping the smallest or keeping the last one, regardless of the configured aggregation.
+//
+//   - queue(name: string) -> MutationRequest
+//     Routes the PipelineRun to the named Kueue LocalQueue by setting the "kueue.x-k8s.io/queue-name"
+//     label. The result takes precedence over a queue label set by the user and over the configured
+//     queueName.
+//
+//   - removeLabel(key: string) -> MutationRequest
+//     Removes the label with the specified key, if set
+//
+//   - removeAnnotation(key: string) -> MutationRequest
+//     Removes the annotation with the specified key, if set
+//
 //   - replace(source: string, search: string, replacement: string) -> string
 //     Replaces all occurrences of search string with replacement string in the source string
 //
+//   - regexReplace(source: string, pattern: string, replacement: string) -> string
+//     Replaces all matches of an RE2 pattern, expanding $1-style references to capture groups
+//
+//   - regexFind(source: string, pattern: string) -> list<string>
+//     Returns the first match of an RE2 pattern followed by its capture groups, or an empty list.
+//     Like for regexReplace(), a literal pattern is checked at compile time.
+//
+//   - split(source: string, separator: string) -> list<string>, join(list: list<string>, separator: string) -> string
+//     Split a string around a separator and join a list of strings with one
+//
+//   - lower(source: string) -> string, upper(source: string) -> string
+//     Convert a string to lower or upper case
+//
+//   - trimPrefix(source: string, prefix: string) -> string
+//     Removes a leading prefix, if present
+//
+//   - truncate(source: string, n: int) -> string
+//     Returns the first n characters of a string. Fails if n is negative.
+//
+//   - sanitizeLabelValue(source: string) -> string
+//     Turns a string into a valid label value: invalid characters become '-', the value is trimmed
+//     to start and end with an alphanumeric, and a value longer than 63 characters is shortened
+//     and suffixed with a hash of the input
+//
+//   - param(name: string) -> dyn
+//     Returns the effective value of a PipelineRun parameter: the value set in spec.params,
+//     falling back to the default declared in spec.pipelineSpec.params. String params return
+//     a string, array params a list<string> and object params a map<string, string>.
+//     Fails if the parameter is neither set nor defaulted.
+//
+//   - hasParam(name: string) -> bool
+//     Reports whether param(name) would return a value
+//
+//   - paramArray(name: string) -> list<string>
+//     Returns the effective value of an array parameter, or an empty list if the parameter
+//     is neither set nor defaulted. Fails if the parameter is not an array.
+//
+//   - tasks() -> list<string>, finallyTasks() -> list<string>
+//     Return the names of the tasks and finally tasks of the embedded spec.pipelineSpec, in order.
+//     Both are empty for a PipelineRun with a pipelineRef.
+//
+//   - hasTask(name: string) -> bool
+//     Reports whether a task or finally task of the embedded spec has the given name
+//
+//   - taskRefs() -> list<map<string, string>>
+//     Describes the taskRef of each task and finally task that has one, with the keys task, name,
+//     kind, resolver and bundle. For remote tasks the name is the "name" resolver param, and the
+//     bundle is the "bundle" param of the bundles resolver.
+//
+//   - matrixWidth(name: string) -> int
+//     Returns the number of TaskRuns a task fans out to with its matrix, 1 without a matrix and 0
+//     for an unknown task. Whole array param references such as "$(params.platforms[*])" are
+//     resolved; references to task results count as a single value.
+//
+//   - pipelineRefResolver() -> string
+//     Returns the resolver of spec.pipelineRef, e.g. "bundles" or "git". Like the other resolver
+//     helpers, it returns an empty string without a pipelineRef or with a local one.
+//
+//   - resolverParam(name: string) -> string
+//     Returns the value of a spec.pipelineRef resolver param, or an empty string if it is not set
+//
+//   - bundleImage() -> string
+//     Returns the "bundle" param of the bundles resolver, or an empty string for other resolvers
+//
+//   - bundleRepository() -> string
+//     Returns bundleImage() without its tag and digest, to match every version of a pipeline
+//
+//   - lookup(table: string, key: string) -> dyn
+//     Returns the value of key in a lookup table passed to CompileConfig with WithTables: a string,
+//     an int or a list<string>. Fails if the key is not in the table. A literal table name is
+//     checked at compile time.
+//
+//   - lookupOr(table: string, key: string, default: dyn) -> dyn
+//     Like lookup(), but returns default if the key is not in the table
+//
+//   - profile(name: string) -> list<MutationRequest>
+//     Returns the mutations of a resource profile passed to CompileConfig with WithProfiles: its
+//     resources, its priority class and its annotations. A literal profile name is checked at
+//     compile time.
+//
+//   - bucket(value: string, n: int) -> int
+//     Returns the stable bucket of value among n buckets: the 64-bit FNV-1a hash of its UTF-8
+//     bytes modulo n. Fails if n is not positive.
+//
+//   - percent(value: string) -> int
+//     Returns bucket(value, 100), e.g. percent(plrNamespace) < 20 for a 20% rollout
+//
+//   - inTimeWindow(window: string, timeZone: string) -> bool
+//     Reports whether now falls inside a recurring weekly window such as "Mon-Fri 08:00-18:00",
+//     "Sat,Sun" or "22:00-06:00", read in the given IANA time zone. The end of a time range is
+//     exclusive and a window spanning midnight belongs to the day it starts on. Literal
+//     arguments are parsed at compile time, so an invalid one fails compilation.
+//
 // # Available CEL Variables
 //
-//   - pipelineRun: map<string, any> - The full PipelineRun object as a CEL-accessible map
+//   - pipelineRun: pipeline.v1.PipelineRun - The full PipelineRun object in its JSON form. Its type
+//     is derived from the Tekton Go types, so unknown fields and type mismatches are rejected at
+//     compile time. Fields with a custom JSON encoding, such as param values, are dyn.
 //   - plrNamespace: string - The namespace of the PipelineRun
 //   - pacEventType: string - Value from label "pipelinesascode.tekton.dev/event-type" (empty if not present)
 //   - pacTestEventType: string - Value from label "pac.test.appstudio.openshift.io/event-type" (empty if not present)
+//   - pac: tektonkueue.Pac - Pipelines as Code metadata: eventType, repository, sha, sourceBranch,
+//     targetBranch, pullRequest, sender and gitProvider, read from the "pipelinesascode.tekton.dev/*"
+//     annotations or, failing that, labels. Every field is a string, present and empty if not set.
+//     Unknown fields are rejected at compile time.
+//   - konflux: tektonkueue.Konflux - Konflux metadata: application, component, snapshot and scenario,
+//     read from the "appstudio.openshift.io/*" and "test.appstudio.openshift.io/scenario" keys.
+//     Unknown fields are rejected at compile time.
+//   - namespaceObject: map<string, map<string, string>> - The "labels" and "annotations" of the
+//     PipelineRun's Namespace, both empty if the Namespace is unknown. The name mirrors
+//     ValidatingAdmissionPolicy, since "namespace" is a reserved word in CEL.
+//   - variables: The results of the configured variables, by name. Only declared when the
+//     configuration has variables.
+//   - now: timestamp - The evaluation time, taken once per admission so that every rule
+//     sees the same value. Callers may fix it through Inputs.Now.
 //
 // # Advanced Usage Examples
 //
@@ -104,6 +272,13 @@
 //	                  p, annotation("kueue.konflux-ci.dev/requests-" + p, "1")
 //	              ) : []`
 //
+// The same expression using the param helpers, which also honor defaults
+// declared in an embedded pipelineSpec:
+//
+//	expression := `paramArray("build-platforms").map(
+//	                  p, annotation("kueue.konflux-ci.dev/requests-" + p, "1")
+//	              )`
+//
 // Using string manipulation with replace function:
 //
 //	expression := `has(pipelineRun.spec.params) &&
@@ -118,7 +293,25 @@
 //
 //   - types.go: Core data types (MutationType, MutationRequest) and validation
 //   - compiler.go: CEL environment setup, compilation, and type checking
+//   - schema.go: CEL object types of the PipelineRun, derived from the Tekton Go types
+//   - rules.go: Compilation of named rules and their when conditions
+//   - variables.go: Shared variables, compiled once and evaluated lazily per PipelineRun
+//   - cost.go: Static cost estimation, runtime cost limits and evaluation timeouts
+//   - conflict.go: Conflict policies for mutations of the same label or annotation
+//   - resources.go: Aggregation of resource mutations and existing resource annotations
+//   - provenance.go: The mutated-by annotation recording which rule mutated each key
+//   - shadow.go: Shadow rules, evaluated and compared with the live result but never applied
 //   - evaluator.go: Runtime program evaluation and result conversion
+//   - context.go: Hidden evaluation context and macros for PipelineRun-aware helpers
+//   - params.go: param(), hasParam() and paramArray() helpers
+//   - tasks.go: Helpers inspecting the tasks of the embedded pipelineSpec
+//   - resolvers.go: Helpers inspecting the remote resolution of the pipelineRef
+//   - strings.go: Regex and string helpers, including sanitizeLabelValue()
+//   - bucket.go: bucket() and percent() helpers for gradual rollouts
+//   - tables.go: lookup() and lookupOr() over the configured lookup tables
+//   - profiles.go: profile() over the configured resource profiles
+//   - metadata.go: The pac and konflux variables built from well-known labels and annotations
+//   - timewindow.go: inTimeWindow() and the parsing of recurring time windows
 //   - mutator.go: CELMutator for convenient mutation application
 //   - metrics.go: Prometheus metrics for monitoring CEL evaluation failures
 //
@@ -1,12 +1,19 @@
 package cel
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"time"
 
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/common/types/ref"
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
 	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // ValidationError indicates that a PipelineRun failed validation during
@@ -39,13 +46,57 @@ func (e *EvaluationError) Unwrap() error {
 	return e.Err
 }
 
+// Inputs carries admission-scoped data that CEL expressions can use in
+// addition to the PipelineRun itself.
+type Inputs struct {
+	// Namespace is the Namespace the PipelineRun is created in. Only its
+	// labels and annotations are exposed to CEL. Nil if the Namespace is
+	// unknown, in which case expressions see empty maps.
+	Namespace metav1.Object
+
+	// Now is the time exposed to CEL as the now variable and used by
+	// inTimeWindow(). Zero means the current time.
+	Now time.Time
+
+	// QueueSource names what defaulted the queue label before CEL ran, such
+	// as "queueRouting[0]" or "queueName". It is recorded as the source of
+	// the label in the mutated-by annotation unless a rule replaces it.
+	// Empty if the queue label was supplied by the user.
+	QueueSource string
+}
+
 // CompiledProgram represents a type-safe compiled CEL program
 // Input: *tekv1.PipelineRun
 // Output: []MutationRequest
 type CompiledProgram struct {
+	name       string // Rule name used in logs, metrics and errors
 	program    cel.Program
 	ast        *cel.Ast
 	expression string // Store original expression for debugging
+
+	// when is the optional guard condition of the rule; nil when the rule
+	// always applies
+	when           cel.Program
+	whenExpression string
+
+	// onError is the error policy of the rule; fallback holds the mutations
+	// of the default policy
+	onError            config.OnErrorPolicy
+	fallback           cel.Program
+	fallbackExpression string
+
+	// shadow rules are evaluated and compared, but never applied
+	shadow bool
+
+	// variables are the variables of the configuration, shared by all its
+	// programs; nil when the configuration has none
+	variables *variableSet
+}
+
+// GetName returns the name of the rule the program was compiled from.
+// Anonymous rules are named after their position, e.g. "expressions[0]".
+func (cp *CompiledProgram) GetName() string {
+	return cp.name
 }
 
 // Evaluate executes the compiled CEL program with a PipelineRun input
@@ -56,50 +107,169 @@ func (cp *CompiledProgram) Evaluate(pipelineRun *tekv1.PipelineRun) ([]*Mutation
 		return nil, fmt.Errorf("pipelineRun cannot be nil")
 	}
 
-	pipelineRunMap, err := structToCELMap(pipelineRun)
+	vars, err := newActivation(pipelineRun, Inputs{})
 	if err != nil {
-		return nil, &ValidationError{Err: fmt.Errorf("failed to convert PipelineRun to map: %w", err)}
+		return nil, err
 	}
 
-	// Create the evaluation context
-	pacEventType := ""
-	pacTestEventType := ""
-	if pipelineRun.Labels != nil {
-		pacEventType = pipelineRun.Labels["pipelinesascode.tekton.dev/event-type"]
-		pacTestEventType = pipelineRun.Labels["pac.test.appstudio.openshift.io/event-type"]
+	return cp.evaluate(context.Background(), vars)
+}
+
+// evaluate executes the compiled CEL program against a prepared activation.
+// The activation is built once per PipelineRun by newActivation so that
+// several programs can share it. A rule whose when condition is false
+// produces no mutations. Evaluation stops when ctx is done.
+func (cp *CompiledProgram) evaluate(ctx context.Context, vars map[string]interface{}) ([]*MutationRequest, error) {
+	if cp.variables != nil {
+		cp.variables.bind(ctx, vars)
 	}
-	vars := map[string]interface{}{
-		"pipelineRun":      pipelineRunMap,
-		"plrNamespace":     pipelineRun.Namespace,
-		"pacEventType":     pacEventType,
-		"pacTestEventType": pacTestEventType,
+
+	if cp.when != nil {
+		out, _, err := cp.when.ContextEval(ctx, vars)
+		if err != nil {
+			return nil, cp.evaluationError(ctx, fmt.Errorf("failed to evaluate when condition %q: %w", cp.whenExpression, err))
+		}
+		if matched, ok := out.Value().(bool); !ok || !matched {
+			RecordEvaluationSuccess(cp.name)
+			return nil, nil
+		}
 	}
 
+	mutations, err := evaluateMutations(ctx, cp.program, cp.expression, vars)
+	if err != nil {
+		return nil, cp.evaluationError(ctx, err)
+	}
+
+	RecordEvaluationSuccess(cp.name)
+	return mutations, nil
+}
+
+// evaluateMutations executes a program returning mutations and validates the result.
+func evaluateMutations(ctx context.Context, program cel.Program, expression string, vars map[string]interface{}) ([]*MutationRequest, error) {
 	// Execute the program
-	out, _, err := cp.program.Eval(vars)
+	out, _, err := program.ContextEval(ctx, vars)
 	if err != nil {
-		RecordEvaluationFailure()
-		return nil, &EvaluationError{Err: fmt.Errorf("failed to evaluate CEL expression %q: %w", cp.expression, err)}
+		return nil, fmt.Errorf("failed to evaluate CEL expression %q: %w", expression, err)
 	}
 
 	// Convert the result to []MutationRequest with validation
 	mutations, err := convertToMutationRequests(out)
 	if err != nil {
-		RecordEvaluationFailure()
-		return nil, &EvaluationError{Err: fmt.Errorf("failed to convert result to MutationRequests for expression %q: %w", cp.expression, err)}
+		return nil, fmt.Errorf("failed to convert result to MutationRequests for expression %q: %w", expression, err)
 	}
 
 	// Validate all mutations
 	for i, mutation := range mutations {
 		if err := mutation.Validate(); err != nil {
-			RecordEvaluationFailure()
-			return nil, &EvaluationError{Err: fmt.Errorf("invalid mutation at index %d for expression %q: %w", i, cp.expression, err)}
+			return nil, fmt.Errorf("invalid mutation at index %d for expression %q: %w", i, expression, err)
 		}
 	}
 
 	return mutations, nil
 }
 
+// handleError applies the onError policy of the rule to the error returned by
+// evaluate. It returns the mutations to apply instead, or the error if the
+// rule must fail. Timeouts always fail, since the evaluation deadline covers
+// all rules and not only the failing one. evaluate has already recorded the
+// failure of the rule, so the outcome of a fallback is recorded separately.
+func (cp *CompiledProgram) handleError(ctx context.Context, vars map[string]interface{}, err error) ([]*MutationRequest, error) {
+	var costErr *CostLimitError
+	if errors.As(err, &costErr) && costErr.Timeout {
+		return nil, err
+	}
+
+	switch cp.onError {
+	case config.OnErrorSkip:
+		return nil, nil
+	case config.OnErrorDefault:
+		mutations, fallbackErr := evaluateMutations(ctx, cp.fallback, cp.fallbackExpression, vars)
+		if fallbackErr != nil {
+			result, err := cp.wrapError(ctx, fmt.Errorf("fallback failed: %w", fallbackErr))
+			RecordFallback(cp.name, result)
+			return nil, err
+		}
+		RecordFallback(cp.name, "success")
+		return mutations, nil
+	default:
+		return nil, err
+	}
+}
+
+// evaluationError records the failure of the rule and wraps it like wrapError.
+func (cp *CompiledProgram) evaluationError(ctx context.Context, err error) error {
+	result, err := cp.wrapError(ctx, err)
+	switch result {
+	case "timeout":
+		RecordEvaluationTimeout(cp.name)
+	case "cost_exceeded":
+		RecordEvaluationCostExceeded(cp.name)
+	default:
+		RecordEvaluationFailure(cp.name)
+	}
+	return err
+}
+
+// wrapError wraps err in an EvaluationError naming the rule and returns the
+// metric result of the failure. Evaluations stopped by the cost budget or
+// the deadline are reported as a CostLimitError.
+func (cp *CompiledProgram) wrapError(ctx context.Context, err error) (string, error) {
+	if costErr := cancellationError(ctx, err); costErr != nil {
+		result := "cost_exceeded"
+		if costErr.Timeout {
+			result = "timeout"
+		}
+		return result, &EvaluationError{Err: fmt.Errorf("rule %q: %w", cp.name, costErr)}
+	}
+	return "failure", &EvaluationError{Err: fmt.Errorf("rule %q: %w", cp.name, err)}
+}
+
+// newActivation builds the variables exposed to CEL expressions for the
+// given PipelineRun and admission inputs.
+func newActivation(pipelineRun *tekv1.PipelineRun, inputs Inputs) (map[string]interface{}, error) {
+	pipelineRunMap, err := structToCELMap(pipelineRun)
+	if err != nil {
+		return nil, &ValidationError{Err: fmt.Errorf("failed to convert PipelineRun to map: %w", err)}
+	}
+
+	pacEventType := ""
+	pacTestEventType := ""
+	if pipelineRun.Labels != nil {
+		pacEventType = pipelineRun.Labels["pipelinesascode.tekton.dev/event-type"]
+		pacTestEventType = pipelineRun.Labels["pac.test.appstudio.openshift.io/event-type"]
+	}
+	now := inputs.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	return map[string]interface{}{
+		"pipelineRun":      pipelineRunMap,
+		"plrNamespace":     pipelineRun.Namespace,
+		"pacEventType":     pacEventType,
+		"pacTestEventType": pacTestEventType,
+		"pac":              metadataToCELMap(pipelineRun, pacFields),
+		"konflux":          metadataToCELMap(pipelineRun, konfluxFields),
+		"namespaceObject":  namespaceToCELMap(inputs.Namespace),
+		"now":              now,
+		contextVariable:    newEvaluationContext(pipelineRun, now),
+	}, nil
+}
+
+// namespaceToCELMap exposes the labels and annotations of a Namespace. Both
+// maps are always present so expressions don't need has() guards.
+func namespaceToCELMap(namespace metav1.Object) map[string]map[string]string {
+	labels := map[string]string{}
+	annotations := map[string]string{}
+	if namespace != nil {
+		maps.Copy(labels, namespace.GetLabels())
+		maps.Copy(annotations, namespace.GetAnnotations())
+	}
+	return map[string]map[string]string{
+		"labels":      labels,
+		"annotations": annotations,
+	}
+}
+
 // GetExpression returns the original CEL expression for debugging
 func (cp *CompiledProgram) GetExpression() string {
 	return cp.expression
@@ -184,12 +354,29 @@ func convertSingleMutation(val interface{}) (*MutationRequest, error) {
 	if err != nil {
 		return nil, err
 	}
+	if mutationType.IsRemoval() && value != "" {
+		return nil, fmt.Errorf("'value' field must be empty for %s mutations", mutationType)
+	}
 
-	return &MutationRequest{
+	mutation := &MutationRequest{
 		Type:  mutationType,
 		Key:   key,
 		Value: value,
-	}, nil
+	}
+
+	// The aggregation is optional and only set by the resource functions
+	if _, exists := mapVal["aggregation"]; exists {
+		aggregation, err := extractStringField(mapVal, "aggregation")
+		if err != nil {
+			return nil, err
+		}
+		mutation.Aggregation = config.ResourceAggregation(aggregation)
+		if err := mutation.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return mutation, nil
 }
 
 // extractMutationType extracts and validates the mutation type from a map
@@ -227,12 +414,19 @@ func extractStringField(mapVal map[string]interface{}, fieldName string) (string
 	return fieldStr, nil
 }
 
+// structToCELMap converts v into its JSON form, with integral numbers as
+// int64 to match the types declared by pipelineRunSchema.
 func structToCELMap(v interface{}) (map[string]interface{}, error) {
 	b, err := json.Marshal(v)
 	if err != nil {
 		return nil, err
 	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
 	var m map[string]interface{}
-	err = json.Unmarshal(b, &m)
-	return m, err
+	if err := decoder.Decode(&m); err != nil {
+		return nil, err
+	}
+	normalizeNumbers(m)
+	return m, nil
 }
@@ -0,0 +1,78 @@
+package cel
+
+import (
+	"github.com/google/cel-go/common/types"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+)
+
+const (
+	// pacTypeName is the CEL object type of the pac variable.
+	pacTypeName = "tektonkueue.Pac"
+	// konfluxTypeName is the CEL object type of the konflux variable.
+	konfluxTypeName = "tektonkueue.Konflux"
+)
+
+// metadataField maps a field of a CEL metadata object to the well-known key
+// it is read from.
+type metadataField struct {
+	name string
+	key  string
+}
+
+// pacFields are the fields of the pac variable. Pipelines as Code sets most
+// of its metadata both as labels and as annotations. Label values are
+// sanitized to fit the label syntax, e.g. branch names lose their slashes,
+// so annotations are read first.
+var pacFields = []metadataField{
+	{name: "eventType", key: "pipelinesascode.tekton.dev/event-type"},
+	{name: "repository", key: "pipelinesascode.tekton.dev/repository"},
+	{name: "sha", key: "pipelinesascode.tekton.dev/sha"},
+	{name: "sourceBranch", key: "pipelinesascode.tekton.dev/source-branch"},
+	{name: "targetBranch", key: "pipelinesascode.tekton.dev/branch"},
+	{name: "pullRequest", key: "pipelinesascode.tekton.dev/pull-request"},
+	{name: "sender", key: "pipelinesascode.tekton.dev/sender"},
+	{name: "gitProvider", key: "pipelinesascode.tekton.dev/git-provider"},
+}
+
+// konfluxFields are the fields of the konflux variable, set by the Konflux
+// build and integration services.
+var konfluxFields = []metadataField{
+	{name: "application", key: "appstudio.openshift.io/application"},
+	{name: "component", key: "appstudio.openshift.io/component"},
+	{name: "snapshot", key: "appstudio.openshift.io/snapshot"},
+	{name: "scenario", key: "test.appstudio.openshift.io/scenario"},
+}
+
+// metadataSchema declares the pac and konflux variables as objects with a
+// fixed set of string fields, so that a misspelled field such as pac.sahh is
+// rejected at compile time rather than failing at admission.
+var metadataSchema = &schema{objects: map[string]*schemaObject{
+	pacTypeName:     newMetadataObject(pacFields),
+	konfluxTypeName: newMetadataObject(konfluxFields),
+}}
+
+// newMetadataObject declares the string fields of a metadata object.
+func newMetadataObject(fields []metadataField) *schemaObject {
+	object := &schemaObject{fields: make(map[string]*types.Type, len(fields))}
+	for _, field := range fields {
+		object.names = append(object.names, field.name)
+		object.fields[field.name] = types.StringType
+	}
+	return object
+}
+
+// metadataToCELMap builds a metadata object from the labels and annotations
+// of the PipelineRun. Every field is present, with an empty value if neither
+// the annotation nor the label is set, so expressions don't need has()
+// guards.
+func metadataToCELMap(pipelineRun *tekv1.PipelineRun, fields []metadataField) map[string]string {
+	values := make(map[string]string, len(fields))
+	for _, field := range fields {
+		value, ok := pipelineRun.Annotations[field.key]
+		if !ok {
+			value = pipelineRun.Labels[field.key]
+		}
+		values[field.name] = value
+	}
+	return values
+}
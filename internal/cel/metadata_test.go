@@ -0,0 +1,114 @@
+package cel
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestMetadataVariables(t *testing.T) {
+	wellKnownLabels := map[string]string{
+		"pipelinesascode.tekton.dev/event-type":   "pull_request",
+		"pipelinesascode.tekton.dev/repository":   "build-service",
+		"pipelinesascode.tekton.dev/sha":          "4f1c2d3",
+		"pipelinesascode.tekton.dev/branch":       "main",
+		"pipelinesascode.tekton.dev/pull-request": "42",
+		"pipelinesascode.tekton.dev/sender":       "octocat",
+		"pipelinesascode.tekton.dev/git-provider": "github",
+		"appstudio.openshift.io/application":      "my-app",
+		"appstudio.openshift.io/component":        "my-component",
+		"appstudio.openshift.io/snapshot":         "my-app-snapshot",
+		"test.appstudio.openshift.io/scenario":    "my-app-e2e",
+	}
+
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expression  string
+		expected    string
+	}{
+		{
+			name:       "pac fields from labels",
+			labels:     wellKnownLabels,
+			expression: `pac.eventType + "," + pac.repository + "," + pac.sha + "," + pac.targetBranch + "," + pac.pullRequest + "," + pac.sender + "," + pac.gitProvider`,
+			expected:   "pull_request,build-service,4f1c2d3,main,42,octocat,github",
+		},
+		{
+			name:   "annotations take precedence over sanitized labels",
+			labels: map[string]string{"pipelinesascode.tekton.dev/branch": "release-1.0"},
+			annotations: map[string]string{
+				"pipelinesascode.tekton.dev/branch":        "release/1.0",
+				"pipelinesascode.tekton.dev/source-branch": "feature/queues",
+			},
+			expression: `pac.sourceBranch + " -> " + pac.targetBranch`,
+			expected:   "feature/queues -> release/1.0",
+		},
+		{
+			name:       "konflux fields",
+			labels:     wellKnownLabels,
+			expression: `konflux.application + "," + konflux.component + "," + konflux.snapshot + "," + konflux.scenario`,
+			expected:   "my-app,my-component,my-app-snapshot,my-app-e2e",
+		},
+		{
+			name:       "missing pac fields are empty",
+			expression: `pac.eventType + "," + pac.repository + "," + pac.sha + "," + pac.sourceBranch + "," + pac.targetBranch + "," + pac.pullRequest + "," + pac.sender + "," + pac.gitProvider`,
+			expected:   ",,,,,,,",
+		},
+		{
+			name:       "missing konflux fields are empty",
+			expression: `konflux.application + "," + konflux.component + "," + konflux.snapshot + "," + konflux.scenario`,
+			expected:   ",,,",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileCELPrograms([]string{`annotation("result", ` + tt.expression + `)`})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			pipelineRun := newRulesPipelineRun("tenant")
+			pipelineRun.Labels = tt.labels
+			pipelineRun.Annotations = tt.annotations
+			mutations, err := programs[0].Evaluate(pipelineRun)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(mutations).To(HaveLen(1))
+			g.Expect(mutations[0].Value).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestMetadataVariables_UnknownField(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		errMsg     string
+	}{
+		{
+			name:       "misspelled pac field",
+			expression: `annotation("result", pac.sahh)`,
+			errMsg:     "undefined field 'sahh'",
+		},
+		{
+			name:       "misspelled konflux field",
+			expression: `annotation("result", konflux.componnet)`,
+			errMsg:     "undefined field 'componnet'",
+		},
+		{
+			name:       "pac field that isn't declared",
+			expression: `annotation("result", pac.branch)`,
+			errMsg:     "undefined field 'branch'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := CompileCELPrograms([]string{tt.expression})
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+		})
+	}
+}
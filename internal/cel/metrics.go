@@ -1,6 +1,8 @@
 package cel
 
 import (
+	"strconv"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
@@ -10,9 +12,22 @@ var (
 	celEvaluationsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "tekton_kueue_cel_evaluations_total",
-			Help: "Total number of CEL evaluations",
+			Help: "Total number of CEL rule evaluations",
+		},
+		// result can be "success", "failure", "cost_exceeded" or "timeout";
+		// rule is the rule name, e.g. "expressions[0]" for anonymous rules
+		[]string{"result", "rule"},
+	)
+
+	// celFallbacksTotal tracks the fallbacks of rules with onError: default.
+	// The failure of the rule itself is counted in celEvaluationsTotal.
+	celFallbacksTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tekton_kueue_cel_fallbacks_total",
+			Help: "Total number of CEL rule fallbacks evaluated after the rule failed",
 		},
-		[]string{"result"}, // result can be "success" or "failure"
+		// result can be "success", "failure", "cost_exceeded" or "timeout"
+		[]string{"result", "rule"},
 	)
 
 	// celMutationsTotal tracks the total number of CEL mutation operations
@@ -23,22 +38,65 @@ var (
 		},
 		[]string{"result"}, // result: "success" or "failure"
 	)
+
+	// celConflictsTotal tracks mutations that conflicted with an earlier
+	// mutation of the same label or annotation
+	celConflictsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tekton_kueue_cel_conflicts_total",
+			Help: "Total number of conflicting CEL mutations of the same label or annotation",
+		},
+		// policy is the conflict policy that resolved the conflict
+		[]string{"policy"},
+	)
+
+	// celShadowEvaluationsTotal tracks the comparisons of shadow rules with
+	// the live result
+	celShadowEvaluationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tekton_kueue_cel_shadow_evaluations_total",
+			Help: "Total number of shadow CEL rule evaluations compared with the live result",
+		},
+		// matched is "true" if the shadow rule agrees with the live result
+		[]string{"rule", "matched"},
+	)
 )
 
 func init() {
 	// Register the metrics with controller-runtime's global registry
 	metrics.Registry.MustRegister(celEvaluationsTotal)
+	metrics.Registry.MustRegister(celFallbacksTotal)
 	metrics.Registry.MustRegister(celMutationsTotal)
+	metrics.Registry.MustRegister(celConflictsTotal)
+	metrics.Registry.MustRegister(celShadowEvaluationsTotal)
+}
+
+// RecordEvaluationFailure increments the counter for CEL evaluation failures of a rule
+func RecordEvaluationFailure(rule string) {
+	celEvaluationsTotal.WithLabelValues("failure", rule).Inc()
 }
 
-// RecordEvaluationFailure increments the counter for CEL evaluation failures
-func RecordEvaluationFailure() {
-	celEvaluationsTotal.WithLabelValues("failure").Inc()
+// RecordEvaluationCostExceeded increments the counter for CEL evaluations of a rule
+// stopped because they went over the cost budget
+func RecordEvaluationCostExceeded(rule string) {
+	celEvaluationsTotal.WithLabelValues("cost_exceeded", rule).Inc()
 }
 
-// RecordEvaluationSuccess increments the counter for successful CEL evaluations
-func RecordEvaluationSuccess() {
-	celEvaluationsTotal.WithLabelValues("success").Inc()
+// RecordEvaluationTimeout increments the counter for CEL evaluations of a rule
+// stopped because the evaluation deadline expired
+func RecordEvaluationTimeout(rule string) {
+	celEvaluationsTotal.WithLabelValues("timeout", rule).Inc()
+}
+
+// RecordEvaluationSuccess increments the counter for successful CEL evaluations of a rule
+func RecordEvaluationSuccess(rule string) {
+	celEvaluationsTotal.WithLabelValues("success", rule).Inc()
+}
+
+// RecordFallback increments the counter for fallback evaluations of a rule
+// by result, one of the results of tekton_kueue_cel_evaluations_total
+func RecordFallback(rule, result string) {
+	celFallbacksTotal.WithLabelValues(result, rule).Inc()
 }
 
 // RecordMutationFailure increments the counter for CEL mutation failures
@@ -50,3 +108,13 @@ func RecordMutationFailure() {
 func RecordMutationSuccess() {
 	celMutationsTotal.WithLabelValues("success").Inc()
 }
+
+// RecordConflict increments the counter for conflicting CEL mutations resolved by a policy
+func RecordConflict(policy string) {
+	celConflictsTotal.WithLabelValues(policy).Inc()
+}
+
+// RecordShadowEvaluation increments the counter for shadow rule evaluations by whether they matched the live result
+func RecordShadowEvaluation(rule string, matched bool) {
+	celShadowEvaluationsTotal.WithLabelValues(rule, strconv.FormatBool(matched)).Inc()
+}
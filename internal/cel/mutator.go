@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"time"
 
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
 	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 // CELMutator applies mutations to PipelineRun objects based on compiled CEL programs.
@@ -23,15 +27,87 @@ import (
 //	}
 //
 //	mutator := &CELMutator{programs: programs}
-//	err = mutator.Mutate(pipelineRun)
+//	err = mutator.Mutate(ctx, pipelineRun, Inputs{})
 type CELMutator struct {
-	programs []*CompiledProgram
+	programs          []*CompiledProgram
+	evaluationTimeout time.Duration
+	conflictPolicy    config.ConflictPolicy
+	priorityClasses   []string
+	// resourceAggregation maps resource names to their aggregation
+	resourceAggregation map[string]config.ResourceAggregation
+	existingResources   config.ExistingResourcePolicy
+	// provenance enables the mutated-by annotation, tagged with generation
+	provenance bool
+	generation string
+}
+
+// DefaultEvaluationTimeout bounds the time spent evaluating all programs for
+// a single PipelineRun when the configuration sets no evaluationTimeout.
+// The admission request's own deadline does not reach the webhook handler,
+// so this one is kept well below the webhook's timeoutSeconds of 10s,
+// leaving time for the rest of the admission.
+const DefaultEvaluationTimeout = 5 * time.Second
+
+// DefaultExistingResources is the policy for resource annotations already
+// set on the PipelineRun when the configuration sets no existingResources.
+// Replacing them keeps users from inflating or pre-seeding the resources
+// that CEL requests.
+const DefaultExistingResources = config.ExistingResourcesOverride
+
+// MutatorOption configures optional behavior of a CELMutator.
+type MutatorOption func(*CELMutator)
+
+// WithEvaluationTimeout bounds the time spent evaluating all programs for a
+// single PipelineRun. Zero leaves evaluation bounded only by the context
+// passed to Mutate.
+func WithEvaluationTimeout(timeout time.Duration) MutatorOption {
+	return func(m *CELMutator) {
+		m.evaluationTimeout = timeout
+	}
+}
+
+// WithConflictPolicy selects how mutations that write the same label or
+// annotation are resolved. priorityClasses lists priority class names from
+// highest to lowest and is used by the highestPriority policy. An empty
+// policy selects lastWins.
+func WithConflictPolicy(policy config.ConflictPolicy, priorityClasses []string) MutatorOption {
+	return func(m *CELMutator) {
+		m.conflictPolicy = policy
+		m.priorityClasses = priorityClasses
+	}
+}
+
+// WithResourceAggregation selects how repeated values of a resource are
+// combined, keyed by the resource name passed to resource(), and how they
+// combine with resource annotations already set on the PipelineRun. Unlisted
+// resources are summed. An empty policy selects aggregate, the behavior of a
+// CELMutator without this option; configurations default to
+// DefaultExistingResources instead.
+func WithResourceAggregation(aggregation map[string]config.ResourceAggregation, existing config.ExistingResourcePolicy) MutatorOption {
+	return func(m *CELMutator) {
+		m.resourceAggregation = aggregation
+		m.existingResources = existing
+	}
+}
+
+// WithProvenance enables the "kueue.konflux-ci.dev/mutated-by" annotation,
+// which maps every label and annotation mutated by CEL to the rules that
+// produced it. generation identifies the configuration the rules came from.
+func WithProvenance(generation string) MutatorOption {
+	return func(m *CELMutator) {
+		m.provenance = true
+		m.generation = generation
+	}
 }
 
 // NewCELMutator creates a new CELMutator with the provided compiled programs.
 // The programs will be evaluated in order when Mutate is called.
-func NewCELMutator(programs []*CompiledProgram) *CELMutator {
-	return &CELMutator{programs: programs}
+func NewCELMutator(programs []*CompiledProgram, opts ...MutatorOption) *CELMutator {
+	m := &CELMutator{programs: programs}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 // Mutate applies all configured CEL mutations to the provided PipelineRun.
@@ -43,15 +119,20 @@ func NewCELMutator(programs []*CompiledProgram) *CELMutator {
 // mutations are applied to the original PipelineRun to avoid permanently
 // overriding cluster-level defaults (e.g., TektonConfig timeouts).
 //
+// Mutations that write the same label or annotation are resolved with the
+// configured conflict policy before any of them is applied.
+//
 // The PipelineRun is modified in-place. If any evaluation fails, the method
 // returns an error and the PipelineRun may be partially modified.
 //
 // Parameters:
+//   - ctx: The context of the admission request
 //   - pipelineRun: The PipelineRun to mutate. Must not be nil.
+//   - inputs: Admission-scoped data exposed to CEL, such as the Namespace
 //
 // Returns:
 //   - error: Any error that occurred during evaluation or mutation
-func (m *CELMutator) Mutate(pipelineRun *tekv1.PipelineRun) error {
+func (m *CELMutator) Mutate(ctx context.Context, pipelineRun *tekv1.PipelineRun, inputs Inputs) error {
 	if pipelineRun == nil {
 		return fmt.Errorf("pipelineRun cannot be nil")
 	}
@@ -68,56 +149,112 @@ func (m *CELMutator) Mutate(pipelineRun *tekv1.PipelineRun) error {
 	// on the original to avoid overriding cluster-level TektonConfig defaults
 	// like timeouts.
 	plrCopy := pipelineRun.DeepCopy()
-	plrCopy.Spec.SetDefaults(context.TODO())
+	plrCopy.Spec.SetDefaults(ctx)
 
-	if errs := plrCopy.Spec.Validate(context.TODO()); errs != nil {
+	if errs := plrCopy.Spec.Validate(ctx); errs != nil {
 		return &ValidationError{Err: fmt.Errorf("invalid pipelinerun: %v", errs)}
 	}
 
-	mutations, err := m.evaluate(plrCopy)
+	mutations, shadows, err := m.evaluate(ctx, plrCopy, inputs)
 	if err != nil {
 		return err
 	}
 
-	for _, mutation := range mutations {
-		pipelineRun, err = mutate(pipelineRun, mutation)
-		if err != nil {
+	mutations, err = m.resolveConflicts(ctx, mutations)
+	if err != nil {
+		RecordMutationFailure()
+		return err
+	}
+
+	if m.existingResources == config.ExistingResourcesRemove {
+		removeResourceAnnotations(pipelineRun)
+	}
+	for _, rm := range mutations {
+		pipelineRun = mutate(pipelineRun, rm.mutation)
+	}
+	if err := m.applyResources(pipelineRun, mutations); err != nil {
+		RecordMutationFailure()
+		return &EvaluationError{Err: fmt.Errorf("failed to apply resource mutations: %w", err)}
+	}
+	if m.provenance {
+		if err := m.recordProvenance(pipelineRun, inputs.QueueSource, mutations); err != nil {
 			RecordMutationFailure()
-			return &EvaluationError{Err: fmt.Errorf("failed to apply mutation (type: %s, key: %s): %w", mutation.Type, mutation.Key, err)}
+			return &EvaluationError{Err: err}
 		}
 	}
+	m.compareShadow(ctx, pipelineRun, shadows)
 
 	RecordMutationSuccess()
 	return nil
 }
 
+// ruleMutation is a mutation together with the name of the rule that produced it.
+type ruleMutation struct {
+	rule     string
+	mutation *MutationRequest
+}
+
 // evaluate runs all compiled programs against the PipelineRun and collects
-// all resulting mutations. Programs are evaluated in order, and all mutations
-// are collected before any are applied.
+// all resulting mutations. Live programs are evaluated in order, and all
+// mutations are collected before any are applied. Shadow programs are
+// evaluated after the live ones.
 //
 // Parameters:
+//   - ctx: The context of the admission request, carrying the logger
 //   - pipelineRun: The PipelineRun to evaluate against
+//   - inputs: Admission-scoped data exposed to CEL
 //
 // Returns:
-//   - []MutationRequest: All mutations from all programs
-//   - error: Any error that occurred during evaluation
-func (m *CELMutator) evaluate(pipelineRun *tekv1.PipelineRun) ([]*MutationRequest, error) {
-	var allMutations []*MutationRequest
+//   - []ruleMutation: All mutations from all live programs, with the rule that produced them
+//   - []shadowResult: The mutations of the shadow programs, to be compared but not applied
+//   - error: Any error that occurred during evaluation of the live programs
+func (m *CELMutator) evaluate(ctx context.Context, pipelineRun *tekv1.PipelineRun, inputs Inputs) ([]ruleMutation, []shadowResult, error) {
+	log := logf.FromContext(ctx)
+
+	vars, err := newActivation(pipelineRun, inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if m.evaluationTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.evaluationTimeout)
+		defer cancel()
+	}
+
+	var allMutations []ruleMutation
 	for _, program := range m.programs {
-		mutations, err := program.Evaluate(pipelineRun)
+		if program.shadow {
+			continue
+		}
+		// Programs without comprehensions never check the deadline, so
+		// check it between programs as well.
+		if ctx.Err() != nil {
+			err := program.evaluationError(ctx, ctx.Err())
+			log.Error(err, "CEL rule failed", "rule", program.GetName())
+			return nil, nil, err
+		}
+		mutations, err := program.evaluate(ctx, vars)
 		if err != nil {
-			return nil, err
+			log.Error(err, "CEL rule failed", "rule", program.GetName(), "onError", program.onError)
+			mutations, err = program.handleError(ctx, vars, err)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		log.V(1).Info("Evaluated CEL rule", "rule", program.GetName(), "mutations", len(mutations))
+		for _, mutation := range mutations {
+			allMutations = append(allMutations, ruleMutation{rule: program.GetName(), mutation: mutation})
 		}
-		allMutations = append(allMutations, mutations...)
 	}
-	RecordEvaluationSuccess()
-	return allMutations, nil
+	return allMutations, m.evaluateShadow(ctx, vars), nil
 }
 
 // mutate applies a single mutation to the PipelineRun's metadata.
-// It handles label, annotation, and resource mutations, creating the respective
-// maps if they don't exist. Resource mutations have special summing behavior
-// for duplicate keys.
+// It handles label, annotation, queue and removal mutations, creating the
+// respective maps if they don't exist. Resource mutations are aggregated per
+// key and applied by applyResources instead. Conflicting mutations of a key
+// are resolved by resolveConflicts before they reach mutate.
 //
 // Parameters:
 //   - pipelineRun: The PipelineRun to mutate
@@ -125,7 +262,7 @@ func (m *CELMutator) evaluate(pipelineRun *tekv1.PipelineRun) ([]*MutationReques
 //
 // Returns:
 //   - *tekv1.PipelineRun: The modified PipelineRun (same instance)
-func mutate(pipelineRun *tekv1.PipelineRun, mutation *MutationRequest) (*tekv1.PipelineRun, error) {
+func mutate(pipelineRun *tekv1.PipelineRun, mutation *MutationRequest) *tekv1.PipelineRun {
 	switch mutation.Type {
 	case MutationTypeLabel:
 		if pipelineRun.Labels == nil {
@@ -137,30 +274,27 @@ func mutate(pipelineRun *tekv1.PipelineRun, mutation *MutationRequest) (*tekv1.P
 			pipelineRun.Annotations = make(map[string]string)
 		}
 		pipelineRun.Annotations[mutation.Key] = mutation.Value
-	case MutationTypeResource:
-		if pipelineRun.Annotations == nil {
-			pipelineRun.Annotations = make(map[string]string)
-		}
-
-		// Parse the new value as integer
-		newValue, err := strconv.Atoi(mutation.Value)
-		if err != nil {
-			// This should never happen because we validate the value in the CEL compiler
-			return nil, fmt.Errorf("failed to parse resource value %q as integer: %w", mutation.Value, err)
+	case MutationTypeRemoveLabel:
+		delete(pipelineRun.Labels, mutation.Key)
+	case MutationTypeRemoveAnnotation:
+		delete(pipelineRun.Annotations, mutation.Key)
+	case MutationTypeQueue:
+		if pipelineRun.Labels == nil {
+			pipelineRun.Labels = make(map[string]string)
 		}
+		pipelineRun.Labels[mutation.Key] = mutation.Value
+	}
+	return pipelineRun
+}
 
-		// Check if the key already exists and sum the values
-		if existingValue, exists := pipelineRun.Annotations[mutation.Key]; exists {
-			existingInt, err := strconv.Atoi(existingValue)
-			if err != nil {
-				// This can happen if the user has manually set the value to a non-integer
-				return nil, fmt.Errorf("failed to parse existing resource value %q as integer for key %q: %w", existingValue, mutation.Key, err)
-			}
-			newValue += existingInt
+// formatQuantity renders a resource quantity for an annotation. Whole decimal
+// quantities are rendered as plain integers, so counters keep their "1000000"
+// form instead of becoming "1M"; everything else uses the canonical form.
+func formatQuantity(quantity resource.Quantity) string {
+	if quantity.Format == resource.DecimalSI {
+		if value, ok := quantity.AsInt64(); ok {
+			return strconv.FormatInt(value, 10)
 		}
-
-		// Store the summed value back as string
-		pipelineRun.Annotations[mutation.Key] = strconv.Itoa(newValue)
 	}
-	return pipelineRun, nil
+	return quantity.String()
 }
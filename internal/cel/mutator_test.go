@@ -1,6 +1,7 @@
 package cel
 
 import (
+	"context"
 	"errors"
 	"maps"
 	"testing"
@@ -408,6 +409,23 @@ func TestCELMutator_Mutate(t *testing.T) {
 			expectedAnnotations: nil,
 			expectErr:           false,
 		},
+		{
+			name: "queue function overrides a user supplied queue label",
+			expressions: []string{
+				`queue(pacEventType == "push" ? "post-merge" : "pre-merge")`,
+			},
+			initialLabels: map[string]string{
+				"kueue.x-k8s.io/queue-name":             "user-queue",
+				"pipelinesascode.tekton.dev/event-type": "push",
+			},
+			initialAnnotations: nil,
+			expectedLabels: map[string]string{
+				"kueue.x-k8s.io/queue-name":             "post-merge",
+				"pipelinesascode.tekton.dev/event-type": "push",
+			},
+			expectedAnnotations: nil,
+			expectErr:           false,
+		},
 		{
 			name: "priority function combined with other mutations",
 			expressions: []string{
@@ -430,9 +448,9 @@ func TestCELMutator_Mutate(t *testing.T) {
 		},
 
 		{
-			name: "accessing parameter with invalid name - should fail",
+			name: "accessing a missing label - should fail",
 			expressions: []string{
-				"annotation('test', pipelineRun.doesNotExist)",
+				"annotation('test', pipelineRun.metadata.labels['does-not-exist'])",
 			},
 			initialLabels:       nil,
 			initialAnnotations:  nil,
@@ -699,6 +717,52 @@ func TestCELMutator_Mutate(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			name: "remove label and annotation",
+			expressions: []string{
+				`plrNamespace == "test-namespace" ? [removeLabel("kueue.x-k8s.io/priority-class")] : []`,
+				`removeAnnotation("kueue.konflux-ci.dev/requests-aws-vm-x")`,
+			},
+			initialLabels: map[string]string{
+				"kueue.x-k8s.io/priority-class": "konflux-release",
+				"env":                           "prod",
+			},
+			initialAnnotations: map[string]string{
+				"kueue.konflux-ci.dev/requests-aws-vm-x": "4",
+			},
+			expectedLabels: map[string]string{
+				"env": "prod",
+			},
+			expectedAnnotations: map[string]string{},
+			expectErr:           false,
+		},
+		{
+			name: "remove missing keys is a no-op",
+			expressions: []string{
+				`[removeLabel("missing"), removeAnnotation("missing")]`,
+			},
+			initialLabels:       nil,
+			initialAnnotations:  nil,
+			expectedLabels:      nil,
+			expectedAnnotations: nil,
+			expectErr:           false,
+		},
+		{
+			name: "later mutations override earlier removals",
+			expressions: []string{
+				`[removeLabel("env"), label("env", "dev")]`,
+				`[priority("high"), removeLabel("kueue.x-k8s.io/priority-class")]`,
+			},
+			initialLabels: map[string]string{
+				"env": "prod",
+			},
+			initialAnnotations: nil,
+			expectedLabels: map[string]string{
+				"env": "dev",
+			},
+			expectedAnnotations: nil,
+			expectErr:           false,
+		},
 		{
 			name: "single resource mutation - new annotation",
 			expressions: []string{
@@ -740,7 +804,7 @@ func TestCELMutator_Mutate(t *testing.T) {
 			expectedAnnotations: nil,
 			expectErr:           true,
 			expectedErrType:     new(*EvaluationError),
-			errMsg:              "failed to parse existing resource value \"invalid\" as integer",
+			errMsg:              "failed to parse existing resource value \"invalid\" as quantity",
 		},
 		{
 			name: "multiple resource mutations - same key summing",
@@ -756,6 +820,37 @@ func TestCELMutator_Mutate(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			name: "resource mutations - quantities summing",
+			expressions: []string{
+				`[resource("cpu", "500m"), resource("memory", "4Gi")]`,
+				`[resource("cpu", 1), resource("memory", "512Mi")]`,
+			},
+			initialLabels: nil,
+			initialAnnotations: map[string]string{
+				"kueue.konflux-ci.dev/requests-cpu": "250m",
+			},
+			expectedLabels: nil,
+			expectedAnnotations: map[string]string{
+				"kueue.konflux-ci.dev/requests-cpu":    "1750m",  // 250m + 500m + 1
+				"kueue.konflux-ci.dev/requests-memory": "4608Mi", // 4Gi + 512Mi
+			},
+			expectErr: false,
+		},
+		{
+			name: "resource mutations - large counters keep integer form",
+			expressions: []string{
+				`resource("aws-vm-x", 1000000)`,
+				`resource("aws-vm-x", "1000000")`,
+			},
+			initialLabels:      nil,
+			initialAnnotations: nil,
+			expectedLabels:     nil,
+			expectedAnnotations: map[string]string{
+				"kueue.konflux-ci.dev/requests-aws-vm-x": "2000000",
+			},
+			expectErr: false,
+		},
 		{
 			name: "mixed mutations with resources",
 			expressions: []string{
@@ -826,7 +921,7 @@ func TestCELMutator_Mutate(t *testing.T) {
 			mutator := NewCELMutator(programs)
 
 			// Apply mutations
-			err = mutator.Mutate(pipelineRun)
+			err = mutator.Mutate(context.Background(), pipelineRun, Inputs{})
 
 			// Check for expected errors
 			if tt.expectErr {
@@ -860,7 +955,7 @@ func TestCELMutator_Mutate_NilPipelineRun(t *testing.T) {
 	g.Expect(err).NotTo(HaveOccurred())
 
 	mutator := NewCELMutator(programs)
-	err = mutator.Mutate(nil)
+	err = mutator.Mutate(context.Background(), nil, Inputs{})
 
 	g.Expect(err).To(HaveOccurred())
 }
@@ -883,7 +978,7 @@ func TestCELMutator_Mutate_ValidationError(t *testing.T) {
 		},
 	}
 
-	err = mutator.Mutate(pipelineRun)
+	err = mutator.Mutate(context.Background(), pipelineRun, Inputs{})
 	g.Expect(err).To(HaveOccurred())
 
 	var validationErr *ValidationError
@@ -907,10 +1002,67 @@ func TestCELMutator_EmptyPrograms(t *testing.T) {
 		},
 	}
 
-	err := mutator.Mutate(pipelineRun)
+	err := mutator.Mutate(context.Background(), pipelineRun, Inputs{})
 	g.Expect(err).NotTo(HaveOccurred())
 
 	// Should not crash or modify the PipelineRun
 	g.Expect(pipelineRun.Labels).To(BeNil())
 	g.Expect(pipelineRun.Annotations).To(BeNil())
 }
+
+func TestCELMutator_Mutate_Namespace(t *testing.T) {
+	expression := `[
+		label("tenant", "tenant" in namespaceObject.labels ? namespaceObject.labels["tenant"] : "none"),
+		annotation("tier", "tier" in namespaceObject.annotations ? namespaceObject.annotations["tier"] : "none")
+	]`
+
+	tests := []struct {
+		name                string
+		namespace           metav1.Object
+		expectedLabels      map[string]string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name: "namespace labels and annotations",
+			namespace: &metav1.PartialObjectMetadata{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-namespace",
+					Labels:      map[string]string{"tenant": "team-a"},
+					Annotations: map[string]string{"tier": "gold"},
+				},
+			},
+			expectedLabels:      map[string]string{"tenant": "team-a"},
+			expectedAnnotations: map[string]string{"tier": "gold"},
+		},
+		{
+			name:                "unknown namespace",
+			namespace:           nil,
+			expectedLabels:      map[string]string{"tenant": "none"},
+			expectedAnnotations: map[string]string{"tier": "none"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileCELPrograms([]string{expression})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			pipelineRun := &tekv1.PipelineRun{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pipeline",
+					Namespace: "test-namespace",
+				},
+				Spec: tekv1.PipelineRunSpec{
+					PipelineRef: &tekv1.PipelineRef{Name: "test-pipeline"},
+				},
+			}
+
+			err = NewCELMutator(programs).Mutate(context.Background(), pipelineRun, Inputs{Namespace: tt.namespace})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(pipelineRun.Labels).To(Equal(tt.expectedLabels))
+			g.Expect(pipelineRun.Annotations).To(Equal(tt.expectedAnnotations))
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package cel
+
+import (
+	"maps"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+)
+
+// createParamFunctions creates the param(), hasParam() and paramArray()
+// helpers. Each helper is exposed to users with the parameter name as its
+// only argument; a macro prepends the hidden evaluation context.
+func createParamFunctions() []cel.EnvOption {
+	return []cel.EnvOption{
+		createContextMacro("param", 1),
+		cel.Function("param",
+			cel.Overload("param_context_string_to_dyn",
+				[]*cel.Type{cel.DynType, cel.StringType},
+				cel.DynType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					ctx, name, errVal := paramArgs("param", lhs, rhs)
+					if errVal != nil {
+						return errVal
+					}
+					value, ok := ctx.params[name]
+					if !ok {
+						return types.NewErr("param %q is not set and has no default", name)
+					}
+					return paramValueToCEL(value)
+				}),
+			),
+		),
+		createContextMacro("hasParam", 1),
+		cel.Function("hasParam",
+			cel.Overload("hasParam_context_string_to_bool",
+				[]*cel.Type{cel.DynType, cel.StringType},
+				cel.BoolType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					ctx, name, errVal := paramArgs("hasParam", lhs, rhs)
+					if errVal != nil {
+						return errVal
+					}
+					_, ok := ctx.params[name]
+					return types.Bool(ok)
+				}),
+			),
+		),
+		createContextMacro("paramArray", 1),
+		cel.Function("paramArray",
+			cel.Overload("paramArray_context_string_to_list",
+				[]*cel.Type{cel.DynType, cel.StringType},
+				cel.ListType(cel.StringType),
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					ctx, name, errVal := paramArgs("paramArray", lhs, rhs)
+					if errVal != nil {
+						return errVal
+					}
+					value, ok := ctx.params[name]
+					if !ok {
+						// A missing param behaves like an empty array so that
+						// expressions such as paramArray("x").map(...) stay safe.
+						return types.NewStringList(types.DefaultTypeAdapter, []string{})
+					}
+					if value.Type != tekv1.ParamTypeArray {
+						return types.NewErr("param %q is of type %q, not %q", name, value.Type, tekv1.ParamTypeArray)
+					}
+					return types.NewStringList(types.DefaultTypeAdapter, value.ArrayVal)
+				}),
+			),
+		),
+	}
+}
+
+// paramArgs validates the arguments passed to the param helpers.
+func paramArgs(function string, lhs, rhs ref.Val) (*evaluationContext, string, ref.Val) {
+	ctx, ok := contextFromVal(lhs)
+	if !ok {
+		return nil, "", types.NewErr("%s function requires the evaluation context", function)
+	}
+	name, ok := rhs.Value().(string)
+	if !ok {
+		return nil, "", types.NewErr("%s function requires a string argument", function)
+	}
+	return ctx, name, nil
+}
+
+// paramValueToCEL converts a Tekton ParamValue to the matching CEL value:
+// string params become strings, array params become list<string> and object
+// params become map<string, string>.
+func paramValueToCEL(value tekv1.ParamValue) ref.Val {
+	switch value.Type {
+	case tekv1.ParamTypeArray:
+		return types.NewStringList(types.DefaultTypeAdapter, value.ArrayVal)
+	case tekv1.ParamTypeObject:
+		return types.NewStringStringMap(types.DefaultTypeAdapter, value.ObjectVal)
+	default:
+		return types.String(value.StringVal)
+	}
+}
+
+// effectiveParams resolves the value of every parameter that the PipelineRun
+// either sets explicitly in spec.params or declares with a default in the
+// embedded spec.pipelineSpec.params. Explicit values take precedence over
+// defaults; for object params the explicit keys are merged over the default
+// keys, matching Tekton's own resolution.
+func effectiveParams(pipelineRun *tekv1.PipelineRun) map[string]tekv1.ParamValue {
+	params := map[string]tekv1.ParamValue{}
+
+	if pipelineRun.Spec.PipelineSpec != nil {
+		for _, spec := range pipelineRun.Spec.PipelineSpec.Params {
+			if spec.Default == nil {
+				continue
+			}
+			params[spec.Name] = *spec.Default
+		}
+	}
+
+	for _, param := range pipelineRun.Spec.Params {
+		value := param.Value
+		if def, ok := params[param.Name]; ok && value.Type == tekv1.ParamTypeObject && def.Type == tekv1.ParamTypeObject {
+			merged := maps.Clone(def.ObjectVal)
+			if merged == nil {
+				merged = map[string]string{}
+			}
+			maps.Copy(merged, value.ObjectVal)
+			value.ObjectVal = merged
+		}
+		params[param.Name] = value
+	}
+
+	return params
+}
@@ -0,0 +1,171 @@
+package cel
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newParamsPipelineRun() *tekv1.PipelineRun {
+	return &tekv1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pipeline",
+			Namespace: "test-namespace",
+		},
+		Spec: tekv1.PipelineRunSpec{
+			Params: []tekv1.Param{
+				{Name: "revision", Value: *tekv1.NewStructuredValues("main")},
+				{Name: "build-platforms", Value: *tekv1.NewStructuredValues("linux/amd64", "linux/arm64")},
+				{Name: "image", Value: *tekv1.NewObject(map[string]string{"tag": "v2"})},
+			},
+			PipelineSpec: &tekv1.PipelineSpec{
+				Params: []tekv1.ParamSpec{
+					{Name: "revision", Type: tekv1.ParamTypeString, Default: tekv1.NewStructuredValues("develop")},
+					{Name: "output-image", Type: tekv1.ParamTypeString, Default: tekv1.NewStructuredValues("quay.io/org/app")},
+					{Name: "extra-platforms", Type: tekv1.ParamTypeArray, Default: &tekv1.ParamValue{Type: tekv1.ParamTypeArray, ArrayVal: []string{"linux/s390x"}}},
+					{Name: "image", Type: tekv1.ParamTypeObject, Default: tekv1.NewObject(map[string]string{"repo": "app", "tag": "latest"})},
+					{Name: "no-default", Type: tekv1.ParamTypeString},
+				},
+			},
+		},
+	}
+}
+
+func TestParamFunctions(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   []MutationRequest
+	}{
+		{
+			name:       "explicit string value",
+			expression: `annotation("revision", param("revision"))`,
+			expected: []MutationRequest{
+				{Type: MutationTypeAnnotation, Key: "revision", Value: "main"},
+			},
+		},
+		{
+			name:       "string default from pipelineSpec",
+			expression: `annotation("output-image", param("output-image"))`,
+			expected: []MutationRequest{
+				{Type: MutationTypeAnnotation, Key: "output-image", Value: "quay.io/org/app"},
+			},
+		},
+		{
+			name:       "explicit array value",
+			expression: `paramArray("build-platforms").map(p, resource(replace(p, "/", "-"), 1))`,
+			expected: []MutationRequest{
+				{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-linux-amd64", Value: "1"},
+				{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-linux-arm64", Value: "1"},
+			},
+		},
+		{
+			name:       "array default from pipelineSpec",
+			expression: `annotation("platform", paramArray("extra-platforms")[0])`,
+			expected: []MutationRequest{
+				{Type: MutationTypeAnnotation, Key: "platform", Value: "linux/s390x"},
+			},
+		},
+		{
+			name:       "missing array param is empty",
+			expression: `annotation("count", string(paramArray("missing").size()))`,
+			expected: []MutationRequest{
+				{Type: MutationTypeAnnotation, Key: "count", Value: "0"},
+			},
+		},
+		{
+			name:       "object param merges explicit keys over defaults",
+			expression: `annotation("image", param("image").repo + ":" + param("image").tag)`,
+			expected: []MutationRequest{
+				{Type: MutationTypeAnnotation, Key: "image", Value: "app:v2"},
+			},
+		},
+		{
+			name:       "array param through param()",
+			expression: `annotation("first", param("build-platforms")[0])`,
+			expected: []MutationRequest{
+				{Type: MutationTypeAnnotation, Key: "first", Value: "linux/amd64"},
+			},
+		},
+		{
+			name: "hasParam",
+			expression: `[
+				label("has-revision", string(hasParam("revision"))),
+				label("has-output-image", string(hasParam("output-image"))),
+				label("has-no-default", string(hasParam("no-default"))),
+				label("has-missing", string(hasParam("missing")))
+			]`,
+			expected: []MutationRequest{
+				{Type: MutationTypeLabel, Key: "has-revision", Value: "true"},
+				{Type: MutationTypeLabel, Key: "has-output-image", Value: "true"},
+				{Type: MutationTypeLabel, Key: "has-no-default", Value: "false"},
+				{Type: MutationTypeLabel, Key: "has-missing", Value: "false"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileCELPrograms([]string{tt.expression})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			mutations, err := programs[0].Evaluate(newParamsPipelineRun())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			g.Expect(mutations).To(HaveLen(len(tt.expected)))
+			for i, expected := range tt.expected {
+				g.Expect(*mutations[i]).To(Equal(expected))
+			}
+		})
+	}
+}
+
+func TestParamFunctions_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		errMsg     string
+	}{
+		{
+			name:       "missing param",
+			expression: `annotation("key", param("missing"))`,
+			errMsg:     `param "missing" is not set and has no default`,
+		},
+		{
+			name:       "param without default",
+			expression: `annotation("key", param("no-default"))`,
+			errMsg:     `param "no-default" is not set and has no default`,
+		},
+		{
+			name:       "paramArray on a string param",
+			expression: `paramArray("revision").map(p, label("platform", p))`,
+			errMsg:     `param "revision" is of type "string", not "array"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileCELPrograms([]string{tt.expression})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			_, err = programs[0].Evaluate(newParamsPipelineRun())
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+		})
+	}
+}
+
+func TestParamFunctions_TypeChecking(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := CompileCELPrograms([]string{`paramArray(1).map(p, label("platform", p))`})
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = CompileCELPrograms([]string{`label("platform", paramArray("build-platforms"))`})
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,106 @@
+package cel
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/konflux-ci/tekton-kueue/pkg/common"
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+)
+
+// WithProfiles makes resource profiles available to profile(). The profiles
+// are validated by CompileConfig.
+func WithProfiles(profiles map[string]config.Profile) CompileOption {
+	return func(o *compileOptions) {
+		o.profiles = profiles
+	}
+}
+
+// ValidateProfiles checks the quantities, priority classes and annotations
+// of the profiles. The webhook also calls it for configurations without
+// rules, which never reach CompileConfig.
+func ValidateProfiles(profiles map[string]config.Profile) error {
+	_, err := compileProfiles(profiles)
+	return err
+}
+
+// compileProfiles validates the profiles and expands each one into the list
+// of mutations returned by profile(): its resources, its priority class and
+// its annotations, each group in key order.
+func compileProfiles(profiles map[string]config.Profile) (map[string]ref.Val, error) {
+	compiled := make(map[string]ref.Val, len(profiles))
+	for _, name := range sortedKeys(profiles) {
+		if name == "" {
+			return nil, fmt.Errorf("profiles: profile name cannot be empty")
+		}
+		profile := profiles[name]
+
+		var mutations []ref.Val
+		for _, key := range sortedKeys(profile.Resources) {
+			quantity := profile.Resources[key]
+			if quantity.Sign() < 0 {
+				return nil, fmt.Errorf("profiles.%s.resources.%s: resource must be positive (>= 0), got %s", name, key, quantity.String())
+			}
+			mutation := newResourceMutation("profile", "", key, quantity)
+			if types.IsError(mutation) {
+				return nil, fmt.Errorf("profiles.%s.resources.%s: %v", name, key, mutation)
+			}
+			mutations = append(mutations, mutation)
+		}
+		if profile.PriorityClass != "" {
+			if err := validateLabelValue(profile.PriorityClass); err != nil {
+				return nil, fmt.Errorf("profiles.%s.priorityClass: invalid priorityClass: %w", name, err)
+			}
+			mutations = append(mutations, types.NewStringInterfaceMap(types.DefaultTypeAdapter, map[string]interface{}{
+				"type":  string(MutationTypeLabel),
+				"key":   common.PriorityClassLabel,
+				"value": profile.PriorityClass,
+			}))
+		}
+		for _, key := range sortedKeys(profile.Annotations) {
+			value := profile.Annotations[key]
+			if err := validateKey(key, "annotation"); err != nil {
+				return nil, fmt.Errorf("profiles.%s.annotations.%s: %w", name, key, err)
+			}
+			if err := validateAnnotationValue(value); err != nil {
+				return nil, fmt.Errorf("profiles.%s.annotations.%s: %w", name, key, err)
+			}
+			mutations = append(mutations, types.NewStringInterfaceMap(types.DefaultTypeAdapter, map[string]interface{}{
+				"type":  string(MutationTypeAnnotation),
+				"key":   key,
+				"value": value,
+			}))
+		}
+		compiled[name] = types.NewRefValList(types.DefaultTypeAdapter, mutations)
+	}
+	return compiled, nil
+}
+
+// createProfileFunctions creates the profile() function over the compiled
+// profiles. It returns a list of MutationRequests, which expressions can
+// concatenate with other mutations. A profile name given as a literal is
+// checked at compile time.
+func createProfileFunctions(profiles map[string]ref.Val) []cel.EnvOption {
+	return []cel.EnvOption{
+		createNameMacro("profile", 1, "profile", profiles),
+		cel.Function("profile",
+			cel.Overload("profile_string_to_mutations",
+				[]*cel.Type{cel.StringType},
+				cel.ListType(cel.MapType(cel.StringType, cel.AnyType)),
+				cel.UnaryBinding(func(val ref.Val) ref.Val {
+					name, ok := val.Value().(string)
+					if !ok {
+						return types.NewErr("profile function requires a string argument")
+					}
+					mutations, found := profiles[name]
+					if !found {
+						return types.NewErr("profile: unknown profile %q", name)
+					}
+					return mutations
+				}),
+			),
+		),
+	}
+}
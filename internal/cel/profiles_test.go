@@ -0,0 +1,147 @@
+package cel
+
+import (
+	"testing"
+
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var testProfiles = map[string]config.Profile{
+	"large": {
+		Resources: map[string]resource.Quantity{
+			"memory":      resource.MustParse("8Gi"),
+			"linux-amd64": resource.MustParse("2"),
+		},
+		PriorityClass: "konflux-low",
+		Annotations:   map[string]string{"example.com/profile": "large"},
+	},
+	"small": {
+		Resources: map[string]resource.Quantity{"linux-amd64": resource.MustParse("1")},
+	},
+}
+
+func TestProfileFunction(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   []MutationRequest
+		errMsg     string
+	}{
+		{
+			name:       "profile",
+			expression: `profile("large")`,
+			expected: []MutationRequest{
+				{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-linux-amd64", Value: "2"},
+				{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-memory", Value: "8Gi"},
+				{Type: MutationTypeLabel, Key: "kueue.x-k8s.io/priority-class", Value: "konflux-low"},
+				{Type: MutationTypeAnnotation, Key: "example.com/profile", Value: "large"},
+			},
+		},
+		{
+			name:       "profile combined with other mutations",
+			expression: `profile("small") + [label("size", "small")]`,
+			expected: []MutationRequest{
+				{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-linux-amd64", Value: "1"},
+				{Type: MutationTypeLabel, Key: "size", Value: "small"},
+			},
+		},
+		{
+			name:       "profile chosen at runtime",
+			expression: `profile(plrNamespace == "tenant" ? "small" : "large")`,
+			expected: []MutationRequest{
+				{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-linux-amd64", Value: "1"},
+			},
+		},
+		{
+			name:       "unknown profile computed at runtime",
+			expression: `profile(plrNamespace + "-large")`,
+			errMsg:     `profile: unknown profile "tenant-large"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileConfig(config.CEL{Expressions: []string{tt.expression}}, WithProfiles(testProfiles))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			mutations, err := programs[0].Evaluate(newRulesPipelineRun("tenant"))
+			if tt.errMsg != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(mutations).To(HaveLen(len(tt.expected)))
+			for i, expected := range tt.expected {
+				g.Expect(*mutations[i]).To(Equal(expected))
+			}
+		})
+	}
+}
+
+func TestCompileConfig_ProfileErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		profiles   map[string]config.Profile
+		expression string
+		errMsg     string
+	}{
+		{
+			name:       "unknown profile",
+			profiles:   testProfiles,
+			expression: `profile("lage")`,
+			errMsg:     `unknown profile "lage", must be one of: [large small]`,
+		},
+		{
+			name:       "unknown profile without profiles",
+			expression: `profile("large")`,
+			errMsg:     `unknown profile "large", must be one of: []`,
+		},
+		{
+			name:       "empty profile name",
+			profiles:   map[string]config.Profile{"": {}},
+			expression: `label("env", "test")`,
+			errMsg:     `profiles: profile name cannot be empty`,
+		},
+		{
+			name: "negative quantity",
+			profiles: map[string]config.Profile{
+				"large": {Resources: map[string]resource.Quantity{"memory": resource.MustParse("-1Gi")}},
+			},
+			expression: `label("env", "test")`,
+			errMsg:     `profiles.large.resources.memory: resource must be positive (>= 0), got -1Gi`,
+		},
+		{
+			name: "invalid resource name",
+			profiles: map[string]config.Profile{
+				"large": {Resources: map[string]resource.Quantity{"linux amd64": resource.MustParse("1")}},
+			},
+			expression: `label("env", "test")`,
+			errMsg:     `profiles.large.resources.linux amd64: profile key validation failed: resource annotation key`,
+		},
+		{
+			name:       "invalid priority class",
+			profiles:   map[string]config.Profile{"large": {PriorityClass: "konflux low"}},
+			expression: `label("env", "test")`,
+			errMsg:     `profiles.large.priorityClass: invalid priorityClass: label value 'konflux low' is invalid`,
+		},
+		{
+			name:       "invalid annotation key",
+			profiles:   map[string]config.Profile{"large": {Annotations: map[string]string{"bad key": "x"}}},
+			expression: `label("env", "test")`,
+			errMsg:     `profiles.large.annotations.bad key: annotation key 'bad key' is invalid`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := CompileConfig(config.CEL{Expressions: []string{tt.expression}}, WithProfiles(tt.profiles))
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package cel
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/konflux-ci/tekton-kueue/pkg/common"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+)
+
+// provenance is the content of the mutated-by annotation. It maps every
+// label and annotation mutated by CEL to the comma-separated names of the
+// rules that produced its value. Several rules only share a key when their
+// resource requests were aggregated. A queue label defaulted by the webhook
+// is mapped to its source instead.
+type provenance struct {
+	Generation  string            `json:"generation,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// newProvenance records the rules behind the mutations that were applied,
+// and queueSource for the queue label unless a rule replaced it.
+func newProvenance(generation, queueSource string, mutations []ruleMutation) *provenance {
+	p := &provenance{Generation: generation}
+	if queueSource != "" {
+		p.Labels = map[string]string{common.QueueLabel: queueSource}
+	}
+	for _, rm := range mutations {
+		target, ok := targetOf(rm.mutation)
+		if !ok {
+			// Resource mutations write annotations
+			target = conflictTarget{label: false, key: rm.mutation.Key}
+		}
+		entries := &p.Annotations
+		if target.label {
+			entries = &p.Labels
+		}
+		if *entries == nil {
+			*entries = map[string]string{}
+		}
+		rules := (*entries)[target.key]
+		if rm.mutation.Type != MutationTypeResource {
+			// The mutation replaces the value, including requested resources
+			// discarded by a removeAnnotation() and a defaulted queue label
+			rules = ""
+		}
+		if rules == "" {
+			(*entries)[target.key] = rm.rule
+		} else if !slices.Contains(strings.Split(rules, ","), rm.rule) {
+			(*entries)[target.key] = rules + "," + rm.rule
+		}
+	}
+	return p
+}
+
+// recordProvenance writes the mutated-by annotation for the applied
+// mutations. A mutated-by annotation supplied by the user is never kept, so
+// the annotation can be trusted.
+//
+// Parameters:
+//   - pipelineRun: The PipelineRun to annotate
+//   - queueSource: What defaulted the queue label before CEL ran; empty if nothing did
+//   - mutations: The mutations that were applied, in evaluation order
+//
+// Returns:
+//   - error: Any error that occurred while encoding the annotation
+func (m *CELMutator) recordProvenance(pipelineRun *tekv1.PipelineRun, queueSource string, mutations []ruleMutation) error {
+	if len(mutations) == 0 && queueSource == "" {
+		delete(pipelineRun.Annotations, common.MutatedByAnnotation)
+		return nil
+	}
+
+	value, err := json.Marshal(newProvenance(m.generation, queueSource, mutations))
+	if err != nil {
+		return fmt.Errorf("failed to encode %s annotation: %w", common.MutatedByAnnotation, err)
+	}
+	if pipelineRun.Annotations == nil {
+		pipelineRun.Annotations = make(map[string]string)
+	}
+	pipelineRun.Annotations[common.MutatedByAnnotation] = string(value)
+	return nil
+}
@@ -0,0 +1,161 @@
+package cel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	. "github.com/onsi/gomega"
+)
+
+func TestCELMutator_Mutate_Provenance(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.CEL
+		annotations map[string]string
+		queueSource string
+		expected    string
+	}{
+		{
+			name: "labels and annotations",
+			cfg: config.CEL{
+				Expressions: []string{`[label("env", "prod"), annotation("owner", "team-a")]`},
+				Rules: []config.Rule{
+					{Name: "release-priority", Mutations: `[priority("konflux-release"), removeLabel("stale")]`},
+					{Name: "release-queue", Mutations: `queue("release")`},
+				},
+			},
+			expected: `{"generation":"0123456789ab",` +
+				`"labels":{"env":"expressions[0]","kueue.x-k8s.io/priority-class":"release-priority","kueue.x-k8s.io/queue-name":"release-queue","stale":"release-priority"},` +
+				`"annotations":{"owner":"expressions[0]"}}`,
+		},
+		{
+			name: "only the winner of a conflict is recorded",
+			cfg: config.CEL{
+				ConflictPolicy: config.ConflictPolicyFirstWins,
+				Rules: []config.Rule{
+					{Name: "team-a", Mutations: `priority("konflux-low")`},
+					{Name: "team-b", Mutations: `priority("konflux-high")`},
+				},
+			},
+			expected: `{"generation":"0123456789ab","labels":{"kueue.x-k8s.io/priority-class":"team-a"}}`,
+		},
+		{
+			name: "all rules requesting a resource are recorded",
+			cfg: config.CEL{
+				Expressions: []string{`resource("cpu", 1)`},
+				Rules: []config.Rule{
+					{Name: "builds", Mutations: `[resource("cpu", 1), resource("cpu", 2)]`},
+					{Name: "tests", Mutations: `resource("cpu", 1)`},
+				},
+			},
+			expected: `{"generation":"0123456789ab",` +
+				`"annotations":{"kueue.konflux-ci.dev/requests-cpu":"expressions[0],builds,tests"}}`,
+		},
+		{
+			name: "resource values removed by a later rule are dropped",
+			cfg: config.CEL{
+				Rules: []config.Rule{
+					{Name: "builds", Mutations: `resource("cpu", 1)`},
+					{Name: "cleanup", Mutations: `removeAnnotation("kueue.konflux-ci.dev/requests-cpu")`},
+					{Name: "tests", Mutations: `resource("cpu", 2)`},
+				},
+			},
+			expected: `{"generation":"0123456789ab",` +
+				`"annotations":{"kueue.konflux-ci.dev/requests-cpu":"cleanup,tests"}}`,
+		},
+		{
+			name: "removal of all resource values is recorded",
+			cfg: config.CEL{
+				Rules: []config.Rule{
+					{Name: "builds", Mutations: `resource("cpu", 1)`},
+					{Name: "cleanup", Mutations: `removeAnnotation("kueue.konflux-ci.dev/requests-cpu")`},
+				},
+			},
+			expected: `{"generation":"0123456789ab",` +
+				`"annotations":{"kueue.konflux-ci.dev/requests-cpu":"cleanup"}}`,
+		},
+		{
+			name: "defaulted queue label is recorded with its source",
+			cfg: config.CEL{
+				Expressions: []string{`label("env", "prod")`},
+			},
+			queueSource: "queueRouting[0]",
+			expected: `{"generation":"0123456789ab",` +
+				`"labels":{"env":"expressions[0]","kueue.x-k8s.io/queue-name":"queueRouting[0]"}}`,
+		},
+		{
+			name: "defaulted queue label is recorded without mutations",
+			cfg: config.CEL{
+				Rules: []config.Rule{
+					{Name: "release-priority", When: `plrNamespace == "release"`, Mutations: `priority("konflux-release")`},
+				},
+			},
+			queueSource: "queueName",
+			expected:    `{"generation":"0123456789ab","labels":{"kueue.x-k8s.io/queue-name":"queueName"}}`,
+		},
+		{
+			name: "queue() replaces the source of the defaulted queue label",
+			cfg: config.CEL{
+				Rules: []config.Rule{
+					{Name: "release-queue", Mutations: `queue("release")`},
+				},
+			},
+			queueSource: "queueName",
+			expected:    `{"generation":"0123456789ab","labels":{"kueue.x-k8s.io/queue-name":"release-queue"}}`,
+		},
+		{
+			name: "annotation supplied by the user is replaced",
+			cfg: config.CEL{
+				Expressions: []string{`label("env", "prod")`},
+			},
+			annotations: map[string]string{"kueue.konflux-ci.dev/mutated-by": `{"labels":{"env":"admin"}}`},
+			expected:    `{"generation":"0123456789ab","labels":{"env":"expressions[0]"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileConfig(tt.cfg)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			pipelineRun := newRulesPipelineRun("tenant")
+			pipelineRun.Annotations = tt.annotations
+			mutator := NewCELMutator(programs,
+				WithConflictPolicy(tt.cfg.ConflictPolicy, nil),
+				WithProvenance("0123456789ab"))
+			err = mutator.Mutate(context.Background(), pipelineRun, Inputs{QueueSource: tt.queueSource})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(pipelineRun.Annotations).To(HaveKeyWithValue("kueue.konflux-ci.dev/mutated-by", tt.expected))
+		})
+	}
+}
+
+func TestCELMutator_Mutate_ProvenanceWithoutMutations(t *testing.T) {
+	g := NewWithT(t)
+
+	programs, err := CompileConfig(config.CEL{Rules: []config.Rule{
+		{Name: "release-priority", When: `plrNamespace == "release"`, Mutations: `priority("konflux-release")`},
+	}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	pipelineRun := newRulesPipelineRun("tenant")
+	pipelineRun.Annotations = map[string]string{"kueue.konflux-ci.dev/mutated-by": `{"labels":{"env":"admin"}}`}
+	err = NewCELMutator(programs, WithProvenance("0123456789ab")).Mutate(context.Background(), pipelineRun, Inputs{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pipelineRun.Annotations).NotTo(HaveKey("kueue.konflux-ci.dev/mutated-by"))
+}
+
+func TestCELMutator_Mutate_ProvenanceDisabled(t *testing.T) {
+	g := NewWithT(t)
+
+	programs, err := CompileCELPrograms([]string{`label("env", "prod")`})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	pipelineRun := newRulesPipelineRun("tenant")
+	err = NewCELMutator(programs).Mutate(context.Background(), pipelineRun, Inputs{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pipelineRun.Annotations).NotTo(HaveKey("kueue.konflux-ci.dev/mutated-by"))
+}
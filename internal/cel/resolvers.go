@@ -0,0 +1,128 @@
+package cel
+
+import (
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+)
+
+// bundlesResolver is the name of the Tekton bundles resolver.
+const bundlesResolver = "bundles"
+
+// createResolverFunctions creates the pipelineRefResolver(), resolverParam(),
+// bundleImage() and bundleRepository() helpers, which inspect the remote
+// resolution of spec.pipelineRef. Without a pipelineRef, or with a local
+// one, they all return an empty string.
+func createResolverFunctions() []cel.EnvOption {
+	return []cel.EnvOption{
+		createContextMacro("pipelineRefResolver", 0),
+		cel.Function("pipelineRefResolver",
+			cel.Overload("pipelineRefResolver_context_to_string",
+				[]*cel.Type{cel.DynType},
+				cel.StringType,
+				cel.UnaryBinding(func(val ref.Val) ref.Val {
+					pipelineRef, errVal := resolverArgs("pipelineRefResolver", val)
+					if errVal != nil {
+						return errVal
+					}
+					return types.String(pipelineRef.Resolver)
+				}),
+			),
+		),
+		createContextMacro("resolverParam", 1),
+		cel.Function("resolverParam",
+			cel.Overload("resolverParam_context_string_to_string",
+				[]*cel.Type{cel.DynType, cel.StringType},
+				cel.StringType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					pipelineRef, errVal := resolverArgs("resolverParam", lhs)
+					if errVal != nil {
+						return errVal
+					}
+					name, ok := rhs.Value().(string)
+					if !ok {
+						return types.NewErr("resolverParam function requires a string argument")
+					}
+					return types.String(resolverParam(pipelineRef.Params, name))
+				}),
+			),
+		),
+		createContextMacro("bundleImage", 0),
+		cel.Function("bundleImage",
+			cel.Overload("bundleImage_context_to_string",
+				[]*cel.Type{cel.DynType},
+				cel.StringType,
+				cel.UnaryBinding(func(val ref.Val) ref.Val {
+					pipelineRef, errVal := resolverArgs("bundleImage", val)
+					if errVal != nil {
+						return errVal
+					}
+					return types.String(bundleImage(pipelineRef))
+				}),
+			),
+		),
+		createContextMacro("bundleRepository", 0),
+		cel.Function("bundleRepository",
+			cel.Overload("bundleRepository_context_to_string",
+				[]*cel.Type{cel.DynType},
+				cel.StringType,
+				cel.UnaryBinding(func(val ref.Val) ref.Val {
+					pipelineRef, errVal := resolverArgs("bundleRepository", val)
+					if errVal != nil {
+						return errVal
+					}
+					return types.String(imageRepository(bundleImage(pipelineRef)))
+				}),
+			),
+		),
+	}
+}
+
+// resolverArgs validates the context passed to the resolver helpers and
+// returns the pipelineRef, which is empty if the PipelineRun has none.
+func resolverArgs(function string, val ref.Val) (*tekv1.PipelineRef, ref.Val) {
+	ctx, ok := contextFromVal(val)
+	if !ok {
+		return nil, types.NewErr("%s function requires the evaluation context", function)
+	}
+	if ctx.pipelineRun.Spec.PipelineRef == nil {
+		return &tekv1.PipelineRef{}, nil
+	}
+	return ctx.pipelineRun.Spec.PipelineRef, nil
+}
+
+// resolverParam returns the string value of a resolver param, or an empty
+// string if it is not set.
+func resolverParam(params tekv1.Params, name string) string {
+	for _, param := range params {
+		if param.Name == name {
+			return param.Value.StringVal
+		}
+	}
+	return ""
+}
+
+// bundleImage returns the bundle of a pipelineRef resolved by the bundles
+// resolver, or an empty string for any other pipelineRef.
+func bundleImage(pipelineRef *tekv1.PipelineRef) string {
+	if pipelineRef.Resolver != bundlesResolver {
+		return ""
+	}
+	return resolverParam(pipelineRef.Params, "bundle")
+}
+
+// imageRepository strips the tag and the digest from an image reference,
+// e.g. "quay.io/org/pipeline:v1@sha256:abc" becomes "quay.io/org/pipeline".
+// A port in the registry host, as in "localhost:5000/pipeline", is kept.
+func imageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
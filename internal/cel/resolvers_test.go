@@ -0,0 +1,131 @@
+package cel
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+)
+
+const testBundle = "quay.io/konflux-ci/tekton-catalog/pipeline-docker-build:0.1@sha256:0123456789abcdef"
+
+func newResolverPipelineRun(resolver string, params map[string]string) *tekv1.PipelineRun {
+	pipelineRun := newRulesPipelineRun("tenant")
+	pipelineRun.Spec.PipelineRef = &tekv1.PipelineRef{
+		ResolverRef: tekv1.ResolverRef{Resolver: tekv1.ResolverName(resolver)},
+	}
+	for _, name := range sortedKeys(params) {
+		pipelineRun.Spec.PipelineRef.Params = append(pipelineRun.Spec.PipelineRef.Params,
+			tekv1.Param{Name: name, Value: *tekv1.NewStructuredValues(params[name])})
+	}
+	return pipelineRun
+}
+
+func TestResolverFunctions(t *testing.T) {
+	bundlesPipelineRun := newResolverPipelineRun("bundles", map[string]string{
+		"name": "docker-build", "bundle": testBundle, "kind": "pipeline",
+	})
+	gitPipelineRun := newResolverPipelineRun("git", map[string]string{
+		"url": "https://github.com/konflux-ci/build-definitions", "revision": "main", "pathInRepo": "pipelines/docker-build.yaml",
+	})
+	localPipelineRun := newRulesPipelineRun("tenant")
+	embeddedPipelineRun := newTasksPipelineRun()
+
+	tests := []struct {
+		name        string
+		expression  string
+		pipelineRun *tekv1.PipelineRun
+		expected    string
+	}{
+		{
+			name:        "bundles resolver",
+			expression:  `pipelineRefResolver()`,
+			pipelineRun: bundlesPipelineRun,
+			expected:    "[bundles]",
+		},
+		{
+			name:        "resolver param",
+			expression:  `resolverParam("name")`,
+			pipelineRun: bundlesPipelineRun,
+			expected:    "[docker-build]",
+		},
+		{
+			name:        "missing resolver param",
+			expression:  `resolverParam("missing")`,
+			pipelineRun: bundlesPipelineRun,
+			expected:    "[]",
+		},
+		{
+			name:        "bundle image",
+			expression:  `bundleImage()`,
+			pipelineRun: bundlesPipelineRun,
+			expected:    "[" + testBundle + "]",
+		},
+		{
+			name:        "bundle repository",
+			expression:  `bundleRepository()`,
+			pipelineRun: bundlesPipelineRun,
+			expected:    "[quay.io/konflux-ci/tekton-catalog/pipeline-docker-build]",
+		},
+		{
+			name:        "git resolver",
+			expression:  `pipelineRefResolver() + ":" + resolverParam("pathInRepo")`,
+			pipelineRun: gitPipelineRun,
+			expected:    "[git:pipelines/docker-build.yaml]",
+		},
+		{
+			name:        "no bundle with the git resolver",
+			expression:  `bundleImage() + bundleRepository()`,
+			pipelineRun: gitPipelineRun,
+			expected:    "[]",
+		},
+		{
+			name:        "local pipelineRef",
+			expression:  `pipelineRefResolver() + resolverParam("name") + bundleImage() + bundleRepository()`,
+			pipelineRun: localPipelineRun,
+			expected:    "[]",
+		},
+		{
+			name:        "embedded pipelineSpec",
+			expression:  `pipelineRefResolver() + resolverParam("name") + bundleImage() + bundleRepository()`,
+			pipelineRun: embeddedPipelineRun,
+			expected:    "[]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileCELPrograms([]string{`annotation("result", "[" + ` + tt.expression + ` + "]")`})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			mutations, err := programs[0].Evaluate(tt.pipelineRun)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(mutations).To(HaveLen(1))
+			g.Expect(mutations[0].Value).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestImageRepository(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{image: "quay.io/org/pipeline", expected: "quay.io/org/pipeline"},
+		{image: "quay.io/org/pipeline:v1", expected: "quay.io/org/pipeline"},
+		{image: "quay.io/org/pipeline@sha256:abc", expected: "quay.io/org/pipeline"},
+		{image: "quay.io/org/pipeline:v1@sha256:abc", expected: "quay.io/org/pipeline"},
+		{image: "localhost:5000/pipeline", expected: "localhost:5000/pipeline"},
+		{image: "localhost:5000/pipeline:v1", expected: "localhost:5000/pipeline"},
+		{image: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(imageRepository(tt.image)).To(Equal(tt.expected))
+		})
+	}
+}
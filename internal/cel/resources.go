@@ -0,0 +1,175 @@
+package cel
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// resourceAnnotationPrefix is the prefix of the annotations that request
+// resources, e.g. "kueue.konflux-ci.dev/requests-linux-amd64".
+const resourceAnnotationPrefix = "kueue.konflux-ci.dev/requests-"
+
+// validResourceAggregations returns all supported resource aggregations
+func validResourceAggregations() []config.ResourceAggregation {
+	return []config.ResourceAggregation{
+		config.ResourceAggregationSum, config.ResourceAggregationMax,
+		config.ResourceAggregationMin, config.ResourceAggregationSet,
+	}
+}
+
+// validExistingResourcePolicies returns all supported policies for existing resource annotations
+func validExistingResourcePolicies() []config.ExistingResourcePolicy {
+	return []config.ExistingResourcePolicy{
+		config.ExistingResourcesAggregate, config.ExistingResourcesOverride, config.ExistingResourcesRemove,
+	}
+}
+
+// validateResourceConfig checks the resource aggregations and the policy for
+// existing resource annotations. An empty policy selects aggregate.
+func validateResourceConfig(cfg config.CEL) error {
+	for name, aggregation := range cfg.ResourceAggregation {
+		if name == "" {
+			return fmt.Errorf("cel.resourceAggregation: resourceAggregation cannot contain an empty resource name")
+		}
+		if !slices.Contains(validResourceAggregations(), aggregation) {
+			return fmt.Errorf("cel.resourceAggregation.%s: invalid resourceAggregation %q for resource %q, must be one of: %v",
+				name, aggregation, name, validResourceAggregations())
+		}
+	}
+	if cfg.ExistingResources != "" && !slices.Contains(validExistingResourcePolicies(), cfg.ExistingResources) {
+		return fmt.Errorf("cel.existingResources: invalid existingResources %q, must be one of: %v",
+			cfg.ExistingResources, validExistingResourcePolicies())
+	}
+	return nil
+}
+
+// aggregationOf returns the aggregation of a resource mutation: the one set
+// by the function that created it, else the one configured for the resource,
+// else sum.
+func (m *CELMutator) aggregationOf(mutation *MutationRequest) config.ResourceAggregation {
+	if mutation.Aggregation != "" {
+		return mutation.Aggregation
+	}
+	if aggregation, ok := m.resourceAggregation[strings.TrimPrefix(mutation.Key, resourceAnnotationPrefix)]; ok {
+		return aggregation
+	}
+	return config.ResourceAggregationSum
+}
+
+// aggregate combines the current value of a resource with a new value.
+func aggregate(aggregation config.ResourceAggregation, current, value resource.Quantity) resource.Quantity {
+	switch aggregation {
+	case config.ResourceAggregationMax:
+		if value.Cmp(current) > 0 {
+			return value
+		}
+		return current
+	case config.ResourceAggregationMin:
+		if value.Cmp(current) < 0 {
+			return value
+		}
+		return current
+	case config.ResourceAggregationSet:
+		return value
+	default:
+		current.Add(value)
+		return current
+	}
+}
+
+// aggregatedResource is the combined value of all mutations of a resource.
+type aggregatedResource struct {
+	key         string
+	aggregation config.ResourceAggregation
+	rule        string
+	quantity    resource.Quantity
+	// removed is set when a later removeAnnotation() of the key discards
+	// the value
+	removed bool
+}
+
+// removeResourceAnnotations removes all resource annotations from the PipelineRun.
+func removeResourceAnnotations(pipelineRun *tekv1.PipelineRun) {
+	for key := range pipelineRun.Annotations {
+		if strings.HasPrefix(key, resourceAnnotationPrefix) {
+			delete(pipelineRun.Annotations, key)
+		}
+	}
+}
+
+// applyResources combines the resource mutations of every key with its
+// aggregation and writes the results to the PipelineRun's annotations.
+// All mutations of a key must use the same aggregation. A value already set
+// on the PipelineRun is handled according to the existing resources policy.
+// To keep mutations in order, a removeAnnotation() of a resource key
+// discards the values requested before it; the removal itself, including of
+// the existing value, is applied by mutate beforehand.
+//
+// Parameters:
+//   - pipelineRun: The PipelineRun to mutate
+//   - mutations: All mutations in evaluation order; other types are ignored
+//
+// Returns:
+//   - error: Mixed aggregations of a key, or an existing value that is not a quantity
+func (m *CELMutator) applyResources(pipelineRun *tekv1.PipelineRun, mutations []ruleMutation) error {
+	var resources []*aggregatedResource
+	byKey := map[string]*aggregatedResource{}
+	for _, rm := range mutations {
+		if rm.mutation.Type == MutationTypeRemoveAnnotation {
+			if res, exists := byKey[rm.mutation.Key]; exists {
+				res.removed = true
+				delete(byKey, rm.mutation.Key)
+			}
+			continue
+		}
+		if rm.mutation.Type != MutationTypeResource {
+			continue
+		}
+
+		value, err := resource.ParseQuantity(rm.mutation.Value)
+		if err != nil {
+			// This should never happen because we validate the value in the CEL compiler
+			return fmt.Errorf("failed to parse resource value %q as quantity (rule: %s, key: %s): %w",
+				rm.mutation.Value, rm.rule, rm.mutation.Key, err)
+		}
+
+		aggregation := m.aggregationOf(rm.mutation)
+		res, exists := byKey[rm.mutation.Key]
+		if !exists {
+			res = &aggregatedResource{key: rm.mutation.Key, aggregation: aggregation, rule: rm.rule, quantity: value}
+			byKey[rm.mutation.Key] = res
+			resources = append(resources, res)
+			continue
+		}
+		if res.aggregation != aggregation {
+			return fmt.Errorf("resource %q is aggregated with %q by rule %q and with %q by rule %q",
+				rm.mutation.Key, res.aggregation, res.rule, aggregation, rm.rule)
+		}
+		res.quantity = aggregate(aggregation, res.quantity, value)
+	}
+
+	if len(byKey) > 0 && pipelineRun.Annotations == nil {
+		pipelineRun.Annotations = make(map[string]string)
+	}
+	for _, res := range resources {
+		if res.removed {
+			continue
+		}
+		quantity := res.quantity
+		if existingValue, exists := pipelineRun.Annotations[res.key]; exists && m.existingResources != config.ExistingResourcesOverride {
+			existingQuantity, err := resource.ParseQuantity(existingValue)
+			if err != nil {
+				// This can happen if the user has manually set the value to a non-quantity
+				return fmt.Errorf("failed to parse existing resource value %q as quantity for key %q: %w", existingValue, res.key, err)
+			}
+			quantity = aggregate(res.aggregation, existingQuantity, quantity)
+		}
+		pipelineRun.Annotations[res.key] = formatQuantity(quantity)
+	}
+	return nil
+}
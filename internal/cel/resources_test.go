@@ -0,0 +1,232 @@
+package cel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	. "github.com/onsi/gomega"
+)
+
+func TestResourceAggregationFunctions(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   MutationRequest
+	}{
+		{
+			name:       "resource has no aggregation",
+			expression: `resource("cpu", 2)`,
+			expected:   MutationRequest{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-cpu", Value: "2"},
+		},
+		{
+			name:       "resourceSum",
+			expression: `resourceSum("cpu", 2)`,
+			expected:   MutationRequest{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-cpu", Value: "2", Aggregation: config.ResourceAggregationSum},
+		},
+		{
+			name:       "resourceMax",
+			expression: `resourceMax("memory", "4Gi")`,
+			expected:   MutationRequest{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-memory", Value: "4Gi", Aggregation: config.ResourceAggregationMax},
+		},
+		{
+			name:       "resourceMin",
+			expression: `resourceMin("cpu", "500m")`,
+			expected:   MutationRequest{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-cpu", Value: "500m", Aggregation: config.ResourceAggregationMin},
+		},
+		{
+			name:       "resourceSet",
+			expression: `resourceSet("cpu", 1)`,
+			expected:   MutationRequest{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-cpu", Value: "1", Aggregation: config.ResourceAggregationSet},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileCELPrograms([]string{tt.expression})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			mutations, err := programs[0].Evaluate(newRulesPipelineRun("tenant"))
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(mutations).To(HaveLen(1))
+			g.Expect(*mutations[0]).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestCELMutator_Mutate_ResourceAggregation(t *testing.T) {
+	tests := []struct {
+		name                string
+		expressions         []string
+		aggregation         map[string]config.ResourceAggregation
+		existing            config.ExistingResourcePolicy
+		annotations         map[string]string
+		expectedAnnotations map[string]string
+		errMsg              string
+	}{
+		{
+			name:                "resources are summed by default",
+			expressions:         []string{`[resource("cpu", 2), resource("cpu", 3)]`},
+			expectedAnnotations: map[string]string{"kueue.konflux-ci.dev/requests-cpu": "5"},
+		},
+		{
+			name:                "max from function",
+			expressions:         []string{`resourceMax("memory", "4Gi")`, `resourceMax("memory", "8Gi")`, `resourceMax("memory", "2Gi")`},
+			expectedAnnotations: map[string]string{"kueue.konflux-ci.dev/requests-memory": "8Gi"},
+		},
+		{
+			name:                "min from function",
+			expressions:         []string{`[resourceMin("cpu", 4), resourceMin("cpu", "1500m")]`},
+			expectedAnnotations: map[string]string{"kueue.konflux-ci.dev/requests-cpu": "1500m"},
+		},
+		{
+			name:                "set from function keeps the last value",
+			expressions:         []string{`resourceSet("cpu", 4)`, `resourceSet("cpu", 2)`},
+			expectedAnnotations: map[string]string{"kueue.konflux-ci.dev/requests-cpu": "2"},
+		},
+		{
+			name:        "max from config",
+			expressions: []string{`[resource("memory", "4Gi"), resource("memory", "8Gi"), resource("cpu", 1), resource("cpu", 1)]`},
+			aggregation: map[string]config.ResourceAggregation{"memory": config.ResourceAggregationMax},
+			expectedAnnotations: map[string]string{
+				"kueue.konflux-ci.dev/requests-memory": "8Gi",
+				"kueue.konflux-ci.dev/requests-cpu":    "2",
+			},
+		},
+		{
+			name:                "function overrides config",
+			expressions:         []string{`[resourceSum("memory", "4Gi"), resourceSum("memory", "8Gi")]`},
+			aggregation:         map[string]config.ResourceAggregation{"memory": config.ResourceAggregationMax},
+			expectedAnnotations: map[string]string{"kueue.konflux-ci.dev/requests-memory": "12Gi"},
+		},
+		{
+			name:        "mixed aggregations",
+			expressions: []string{`resource("cpu", 1)`, `resourceMax("cpu", 2)`},
+			errMsg:      `resource "kueue.konflux-ci.dev/requests-cpu" is aggregated with "sum" by rule "expressions[0]" and with "max" by rule "expressions[1]"`,
+		},
+		{
+			name:                "existing value is aggregated by default",
+			expressions:         []string{`resourceMax("cpu", 2)`},
+			annotations:         map[string]string{"kueue.konflux-ci.dev/requests-cpu": "4"},
+			expectedAnnotations: map[string]string{"kueue.konflux-ci.dev/requests-cpu": "4"},
+		},
+		{
+			name:                "existing value is replaced by set",
+			expressions:         []string{`resourceSet("cpu", 2)`},
+			annotations:         map[string]string{"kueue.konflux-ci.dev/requests-cpu": "4"},
+			expectedAnnotations: map[string]string{"kueue.konflux-ci.dev/requests-cpu": "2"},
+		},
+		{
+			name:                "removal after a resource discards it",
+			expressions:         []string{`[resource("cpu", 1), removeAnnotation("kueue.konflux-ci.dev/requests-cpu")]`},
+			annotations:         map[string]string{"kueue.konflux-ci.dev/requests-cpu": "4"},
+			expectedAnnotations: map[string]string{},
+		},
+		{
+			name: "removal in a later rule discards the resource",
+			expressions: []string{
+				`[resource("cpu", 1), resource("memory", "1Gi")]`,
+				`removeAnnotation("kueue.konflux-ci.dev/requests-cpu")`,
+			},
+			expectedAnnotations: map[string]string{"kueue.konflux-ci.dev/requests-memory": "1Gi"},
+		},
+		{
+			name: "resource after a removal replaces the existing value",
+			expressions: []string{
+				`[resource("cpu", 2), removeAnnotation("kueue.konflux-ci.dev/requests-cpu"), resource("cpu", 1)]`,
+			},
+			annotations:         map[string]string{"kueue.konflux-ci.dev/requests-cpu": "4"},
+			expectedAnnotations: map[string]string{"kueue.konflux-ci.dev/requests-cpu": "1"},
+		},
+		{
+			name:        "existing value is overridden",
+			expressions: []string{`resource("cpu", 2)`},
+			existing:    config.ExistingResourcesOverride,
+			annotations: map[string]string{
+				"kueue.konflux-ci.dev/requests-cpu":    "invalid",
+				"kueue.konflux-ci.dev/requests-memory": "1Gi",
+			},
+			expectedAnnotations: map[string]string{
+				"kueue.konflux-ci.dev/requests-cpu":    "2",
+				"kueue.konflux-ci.dev/requests-memory": "1Gi",
+			},
+		},
+		{
+			name:        "existing values are removed",
+			expressions: []string{`resource("cpu", 2)`},
+			existing:    config.ExistingResourcesRemove,
+			annotations: map[string]string{
+				"kueue.konflux-ci.dev/requests-cpu":    "4",
+				"kueue.konflux-ci.dev/requests-memory": "1Gi",
+				"owner":                                "team-a",
+			},
+			expectedAnnotations: map[string]string{
+				"kueue.konflux-ci.dev/requests-cpu": "2",
+				"owner":                             "team-a",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileCELPrograms(tt.expressions)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			pipelineRun := newRulesPipelineRun("tenant")
+			pipelineRun.Annotations = tt.annotations
+			mutator := NewCELMutator(programs, WithResourceAggregation(tt.aggregation, tt.existing))
+			err = mutator.Mutate(context.Background(), pipelineRun, Inputs{})
+			if tt.errMsg != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(pipelineRun.Annotations).To(Equal(tt.expectedAnnotations))
+		})
+	}
+}
+
+func TestCompileConfig_ResourceErrors(t *testing.T) {
+	expressions := []string{`resource("cpu", 1)`}
+
+	tests := []struct {
+		name   string
+		cfg    config.CEL
+		errMsg string
+	}{
+		{
+			name: "invalid aggregation",
+			cfg: config.CEL{
+				Expressions:         expressions,
+				ResourceAggregation: map[string]config.ResourceAggregation{"cpu": "avg"},
+			},
+			errMsg: `invalid resourceAggregation "avg" for resource "cpu"`,
+		},
+		{
+			name: "empty resource name",
+			cfg: config.CEL{
+				Expressions:         expressions,
+				ResourceAggregation: map[string]config.ResourceAggregation{"": config.ResourceAggregationMax},
+			},
+			errMsg: "resourceAggregation cannot contain an empty resource name",
+		},
+		{
+			name:   "invalid existing resources policy",
+			cfg:    config.CEL{Expressions: expressions, ExistingResources: "ignore"},
+			errMsg: `invalid existingResources "ignore"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := CompileConfig(tt.cfg)
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+		})
+	}
+}
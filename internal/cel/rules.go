@@ -0,0 +1,168 @@
+package cel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// CompileConfig compiles the CEL rules of a configuration into type-safe programs.
+// The anonymous rules from Expressions come first, named after their position
+// (e.g. "expressions[0]"), followed by the named Rules in declaration order.
+// The Variables are compiled first and shared by all programs. Errors start
+// with the path of the offending field in the configuration, e.g.
+// "cel.rules[3].when".
+func CompileConfig(cfg config.CEL, opts ...CompileOption) ([]*CompiledProgram, error) {
+	var options compileOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if len(cfg.Expressions) == 0 && len(cfg.Rules) == 0 {
+		return nil, fmt.Errorf("expressions list cannot be empty")
+	}
+	if err := validateConflictPolicy(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateResourceConfig(cfg); err != nil {
+		return nil, err
+	}
+	if err := ValidateTables(options.tables); err != nil {
+		return nil, err
+	}
+	profiles, err := compileProfiles(options.profiles)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := createCELEnvironment()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	if env, err = env.Extend(createTableFunctions(options.tables)...); err != nil {
+		return nil, fmt.Errorf("failed to declare tables: %w", err)
+	}
+	if env, err = env.Extend(createProfileFunctions(profiles)...); err != nil {
+		return nil, fmt.Errorf("failed to declare profiles: %w", err)
+	}
+	var variables *variableSet
+	if len(cfg.Variables) > 0 {
+		if env, variables, err = compileVariables(env, cfg.Variables, cfg.CostLimit); err != nil {
+			return nil, err
+		}
+	}
+
+	programs := make([]*CompiledProgram, 0, len(cfg.Expressions)+len(cfg.Rules))
+	for i, expr := range cfg.Expressions {
+		if expr == "" {
+			return nil, fmt.Errorf("cel.expressions[%d]: expression cannot be empty", i)
+		}
+
+		program, err := compileSingleExpression(env, expr, cfg.CostLimit)
+		if err != nil {
+			return nil, fmt.Errorf("cel.expressions[%d]: failed to compile expression %q: %w", i, expr, err)
+		}
+		program.name = fmt.Sprintf("expressions[%d]", i)
+		program.variables = variables
+		programs = append(programs, program)
+	}
+
+	names := make(map[string]bool, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		if err := validateRuleName(rule.Name); err != nil {
+			return nil, fmt.Errorf("cel.rules[%d].name: %w", i, err)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("cel.rules[%d].name: duplicate rule name %q", i, rule.Name)
+		}
+		names[rule.Name] = true
+
+		program, err := compileRule(env, fmt.Sprintf("cel.rules[%d]", i), rule, cfg.CostLimit)
+		if err != nil {
+			return nil, err
+		}
+		program.variables = variables
+		programs = append(programs, program)
+	}
+
+	return programs, nil
+}
+
+// compileRule compiles the mutations and the optional when condition of a
+// named rule. Errors are prefixed with the path of the offending field below
+// path, the path of the rule.
+func compileRule(env *cel.Env, path string, rule config.Rule, costLimit uint64) (*CompiledProgram, error) {
+	fieldError := func(field string, err error) error {
+		return fmt.Errorf("%s.%s: failed to compile rule %q: %w", path, field, rule.Name, err)
+	}
+	if rule.Mutations == "" {
+		return nil, fieldError("mutations", fmt.Errorf("mutations cannot be empty"))
+	}
+
+	program, err := compileSingleExpression(env, rule.Mutations, costLimit)
+	if err != nil {
+		return nil, fieldError("mutations", err)
+	}
+	program.name = rule.Name
+
+	if rule.When != "" {
+		ast, issues := env.Compile(rule.When)
+		if issues != nil && issues.Err() != nil {
+			return nil, fieldError("when", fmt.Errorf("type checking failed for when condition %q: %w", rule.When, issues.Err()))
+		}
+		if ast.OutputType() != cel.BoolType {
+			return nil, fieldError("when", fmt.Errorf("when condition %q must return bool, got %v", rule.When, ast.OutputType()))
+		}
+		if err := checkCost(env, ast, costLimit); err != nil {
+			return nil, fieldError("when", fmt.Errorf("cost check failed for when condition %q: %w", rule.When, err))
+		}
+		when, err := env.Program(ast, programOptions(costLimit)...)
+		if err != nil {
+			return nil, fieldError("when", fmt.Errorf("program creation failed for when condition %q: %w", rule.When, err))
+		}
+		program.when = when
+		program.whenExpression = rule.When
+	}
+
+	switch rule.OnError {
+	case "", config.OnErrorFail, config.OnErrorSkip:
+		if rule.Fallback != "" {
+			return nil, fieldError("fallback", fmt.Errorf("fallback is only allowed with onError %q", config.OnErrorDefault))
+		}
+	case config.OnErrorDefault:
+		if rule.Fallback == "" {
+			return nil, fieldError("fallback", fmt.Errorf("onError %q requires a fallback", config.OnErrorDefault))
+		}
+		fallback, err := compileSingleExpression(env, rule.Fallback, costLimit)
+		if err != nil {
+			return nil, fieldError("fallback", fmt.Errorf("failed to compile fallback: %w", err))
+		}
+		program.fallback = fallback.program
+		program.fallbackExpression = rule.Fallback
+	default:
+		return nil, fieldError("onError", fmt.Errorf("invalid onError %q, must be one of: %v", rule.OnError,
+			[]config.OnErrorPolicy{config.OnErrorFail, config.OnErrorSkip, config.OnErrorDefault}))
+	}
+	if rule.OnError != "" {
+		program.onError = rule.OnError
+	}
+	program.shadow = rule.Shadow
+
+	return program, nil
+}
+
+// validateRuleName checks that a rule name is usable in logs, metric labels and
+// error messages. Names follow the label value syntax, which also keeps them
+// distinct from the "expressions[N]" names of anonymous rules.
+func validateRuleName(name string) error {
+	if name == "" {
+		return fmt.Errorf("rule name cannot be empty")
+	}
+	if errs := validation.IsValidLabelValue(name); len(errs) > 0 {
+		return fmt.Errorf("rule name %q is invalid: %s", name, strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package cel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newRulesPipelineRun(namespace string) *tekv1.PipelineRun {
+	return &tekv1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pipeline",
+			Namespace: namespace,
+		},
+		Spec: tekv1.PipelineRunSpec{
+			PipelineRef: &tekv1.PipelineRef{Name: "test-pipeline"},
+		},
+	}
+}
+
+func TestCompileConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	programs, err := CompileConfig(config.CEL{
+		Expressions: []string{`label("env", "test")`},
+		Rules: []config.Rule{
+			{
+				Name:        "release-priority",
+				Description: "Release pipelines run first",
+				When:        `plrNamespace == "release"`,
+				Mutations:   `priority("konflux-release")`,
+			},
+			{
+				Name:      "owner",
+				Mutations: `annotation("owner", "platform")`,
+			},
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(programs).To(HaveLen(3))
+	g.Expect(programs[0].GetName()).To(Equal("expressions[0]"))
+	g.Expect(programs[1].GetName()).To(Equal("release-priority"))
+	g.Expect(programs[2].GetName()).To(Equal("owner"))
+}
+
+func TestCompileConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.CEL
+		errMsg string
+	}{
+		{
+			name:   "no expressions or rules",
+			cfg:    config.CEL{},
+			errMsg: "expressions list cannot be empty",
+		},
+		{
+			name:   "rule without name",
+			cfg:    config.CEL{Rules: []config.Rule{{Mutations: `label("env", "test")`}}},
+			errMsg: "cel.rules[0].name: rule name cannot be empty",
+		},
+		{
+			name:   "rule with invalid name",
+			cfg:    config.CEL{Rules: []config.Rule{{Name: "expressions[0]", Mutations: `label("env", "test")`}}},
+			errMsg: `rule name "expressions[0]" is invalid`,
+		},
+		{
+			name: "duplicate rule names",
+			cfg: config.CEL{Rules: []config.Rule{
+				{Name: "env", Mutations: `label("env", "test")`},
+				{Name: "env", Mutations: `label("env", "prod")`},
+			}},
+			errMsg: `cel.rules[1].name: duplicate rule name "env"`,
+		},
+		{
+			name:   "rule without mutations",
+			cfg:    config.CEL{Rules: []config.Rule{{Name: "env"}}},
+			errMsg: `cel.rules[0].mutations: failed to compile rule "env": mutations cannot be empty`,
+		},
+		{
+			name:   "when condition is not a bool",
+			cfg:    config.CEL{Rules: []config.Rule{{Name: "env", When: `plrNamespace`, Mutations: `label("env", "test")`}}},
+			errMsg: `cel.rules[0].when: failed to compile rule "env": when condition "plrNamespace" must return bool, got string`,
+		},
+		{
+			name:   "when condition does not type check",
+			cfg:    config.CEL{Rules: []config.Rule{{Name: "env", When: `unknownVariable`, Mutations: `label("env", "test")`}}},
+			errMsg: `cel.rules[0].when: failed to compile rule "env": type checking failed for when condition`,
+		},
+		{
+			name:   "invalid mutations",
+			cfg:    config.CEL{Rules: []config.Rule{{Name: "env", Mutations: `"not a mutation"`}}},
+			errMsg: `cel.rules[0].mutations: failed to compile rule "env": invalid return type`,
+		},
+		{
+			name:   "invalid onError",
+			cfg:    config.CEL{Rules: []config.Rule{{Name: "env", Mutations: `label("env", "test")`, OnError: "ignore"}}},
+			errMsg: `cel.rules[0].onError: failed to compile rule "env": invalid onError "ignore"`,
+		},
+		{
+			name:   "onError default without fallback",
+			cfg:    config.CEL{Rules: []config.Rule{{Name: "env", Mutations: `label("env", "test")`, OnError: config.OnErrorDefault}}},
+			errMsg: `failed to compile rule "env": onError "default" requires a fallback`,
+		},
+		{
+			name: "fallback without onError default",
+			cfg: config.CEL{Rules: []config.Rule{
+				{Name: "env", Mutations: `label("env", "test")`, OnError: config.OnErrorSkip, Fallback: `label("env", "none")`},
+			}},
+			errMsg: `failed to compile rule "env": fallback is only allowed with onError "default"`,
+		},
+		{
+			name: "invalid fallback",
+			cfg: config.CEL{Rules: []config.Rule{
+				{Name: "env", Mutations: `label("env", "test")`, OnError: config.OnErrorDefault, Fallback: `"not a mutation"`},
+			}},
+			errMsg: `cel.rules[0].fallback: failed to compile rule "env": failed to compile fallback: invalid return type`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := CompileConfig(tt.cfg)
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+		})
+	}
+}
+
+func TestCELMutator_Mutate_Rules(t *testing.T) {
+	cfg := config.CEL{
+		Rules: []config.Rule{
+			{
+				Name:      "release-priority",
+				When:      `plrNamespace == "release"`,
+				Mutations: `priority("konflux-release")`,
+			},
+			{
+				Name:      "owner",
+				Mutations: `annotation("owner", "platform")`,
+			},
+		},
+	}
+
+	tests := []struct {
+		name                string
+		namespace           string
+		expectedLabels      map[string]string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:                "when condition matches",
+			namespace:           "release",
+			expectedLabels:      map[string]string{"kueue.x-k8s.io/priority-class": "konflux-release"},
+			expectedAnnotations: map[string]string{"owner": "platform"},
+		},
+		{
+			name:                "when condition does not match",
+			namespace:           "tenant",
+			expectedLabels:      nil,
+			expectedAnnotations: map[string]string{"owner": "platform"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileConfig(cfg)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			pipelineRun := newRulesPipelineRun(tt.namespace)
+			err = NewCELMutator(programs).Mutate(context.Background(), pipelineRun, Inputs{})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(pipelineRun.Labels).To(Equal(tt.expectedLabels))
+			g.Expect(pipelineRun.Annotations).To(Equal(tt.expectedAnnotations))
+		})
+	}
+}
+
+func TestCELMutator_Mutate_RuleErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.CEL
+		errMsg string
+	}{
+		{
+			name:   "mutations fail",
+			cfg:    config.CEL{Rules: []config.Rule{{Name: "bad-param", Mutations: `label("revision", param("missing"))`}}},
+			errMsg: `rule "bad-param": failed to evaluate CEL expression`,
+		},
+		{
+			name:   "when condition fails",
+			cfg:    config.CEL{Rules: []config.Rule{{Name: "bad-when", When: `param("missing") == "x"`, Mutations: `label("env", "test")`}}},
+			errMsg: `rule "bad-when": failed to evaluate when condition`,
+		},
+		{
+			name:   "anonymous rule fails",
+			cfg:    config.CEL{Expressions: []string{`label("env", "test")`, `label("revision", param("missing"))`}},
+			errMsg: `rule "expressions[1]"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileConfig(tt.cfg)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			err = NewCELMutator(programs).Mutate(context.Background(), newRulesPipelineRun("tenant"), Inputs{})
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+
+			var evalErr *EvaluationError
+			g.Expect(errors.As(err, &evalErr)).To(BeTrue())
+		})
+	}
+}
+
+func TestCELMutator_Mutate_OnError(t *testing.T) {
+	tests := []struct {
+		name           string
+		rule           config.Rule
+		expectedLabels map[string]string
+		errMsg         string
+		// fallbackResult is the result recorded for the fallback, if any
+		fallbackResult string
+	}{
+		{
+			name:   "fail",
+			rule:   config.Rule{Name: "on-error-fail", Mutations: `label("revision", param("missing"))`, OnError: config.OnErrorFail},
+			errMsg: `rule "on-error-fail": failed to evaluate CEL expression`,
+		},
+		{
+			name:           "skip",
+			rule:           config.Rule{Name: "on-error-skip", Mutations: `label("revision", param("missing"))`, OnError: config.OnErrorSkip},
+			expectedLabels: map[string]string{"env": "test"},
+		},
+		{
+			name:           "skip a failing when condition",
+			rule:           config.Rule{Name: "on-error-skip-when", When: `pipelineRun.metadata.labels["does-not-exist"] == "x"`, Mutations: `label("revision", "x")`, OnError: config.OnErrorSkip},
+			expectedLabels: map[string]string{"env": "test"},
+		},
+		{
+			name:           "skip an invalid label value",
+			rule:           config.Rule{Name: "on-error-skip-value", Mutations: `label("revision", "not a valid label value")`, OnError: config.OnErrorSkip},
+			expectedLabels: map[string]string{"env": "test"},
+		},
+		{
+			name: "default",
+			rule: config.Rule{
+				Name:      "on-error-default",
+				Mutations: `label("revision", param("missing"))`,
+				OnError:   config.OnErrorDefault,
+				Fallback:  `label("revision", "unknown")`,
+			},
+			expectedLabels: map[string]string{"env": "test", "revision": "unknown"},
+			fallbackResult: "success",
+		},
+		{
+			name: "default with a failing fallback",
+			rule: config.Rule{
+				Name:      "on-error-default-fails",
+				Mutations: `label("revision", param("missing"))`,
+				OnError:   config.OnErrorDefault,
+				Fallback:  `label("revision", param("missing"))`,
+			},
+			errMsg:         `rule "on-error-default-fails": fallback failed`,
+			fallbackResult: "failure",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileConfig(config.CEL{Rules: []config.Rule{
+				tt.rule,
+				{Name: "env", Mutations: `label("env", "test")`},
+			}})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			failures := testutil.ToFloat64(celEvaluationsTotal.WithLabelValues("failure", tt.rule.Name))
+			successes := testutil.ToFloat64(celEvaluationsTotal.WithLabelValues("success", tt.rule.Name))
+			fallbacks := map[string]float64{}
+			for _, result := range []string{"success", "failure"} {
+				fallbacks[result] = testutil.ToFloat64(celFallbacksTotal.WithLabelValues(result, tt.rule.Name))
+			}
+			pipelineRun := newRulesPipelineRun("tenant")
+			err = NewCELMutator(programs).Mutate(context.Background(), pipelineRun, Inputs{})
+
+			// The rule evaluation is counted once, whatever its fallback does
+			g.Expect(testutil.ToFloat64(celEvaluationsTotal.WithLabelValues("failure", tt.rule.Name))).To(Equal(failures + 1))
+			g.Expect(testutil.ToFloat64(celEvaluationsTotal.WithLabelValues("success", tt.rule.Name))).To(Equal(successes))
+			for result, count := range fallbacks {
+				if result == tt.fallbackResult {
+					count++
+				}
+				g.Expect(testutil.ToFloat64(celFallbacksTotal.WithLabelValues(result, tt.rule.Name))).To(Equal(count), "fallback result %q", result)
+			}
+			if tt.errMsg != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(pipelineRun.Labels).To(Equal(tt.expectedLabels))
+		})
+	}
+}
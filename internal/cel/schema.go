@@ -0,0 +1,264 @@
+package cel
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// stringTypes are types with a custom JSON encoding that is always a string.
+// Other types with a custom JSON encoding are declared as dyn.
+var stringTypes = map[reflect.Type]bool{
+	reflect.TypeFor[metav1.Time]():       true,
+	reflect.TypeFor[metav1.MicroTime]():  true,
+	reflect.TypeFor[metav1.Duration]():   true,
+	reflect.TypeFor[resource.Quantity](): true,
+}
+
+// pipelineRunSchema declares the structure of the pipelineRun variable. It is
+// derived from the Tekton Go types following the encoding/json rules, since
+// the variable is the JSON form of the PipelineRun: fields are named after
+// their json tags, embedded structs are inlined, and types with a custom JSON
+// encoding, such as the value of a param, are dynamic.
+var pipelineRunSchema = newSchema(reflect.TypeFor[tekv1.PipelineRun]())
+
+// schema holds the CEL object types of a Go struct and of the structs it
+// references, by CEL type name.
+type schema struct {
+	root    *types.Type
+	objects map[string]*schemaObject
+}
+
+// schemaObject holds the fields of a CEL object type.
+type schemaObject struct {
+	names  []string
+	fields map[string]*types.Type
+}
+
+// newSchema builds the schema of the struct type t.
+func newSchema(t reflect.Type) *schema {
+	s := &schema{objects: map[string]*schemaObject{}}
+	s.root = s.declType(t)
+	return s
+}
+
+// declType returns the CEL type of the JSON encoding of t, declaring the
+// object types of the structs it references.
+func (s *schema) declType(t reflect.Type) *types.Type {
+	if stringTypes[t] {
+		return types.StringType
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return types.DynType
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return types.StringType
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.declType(t.Elem())
+	case reflect.String:
+		return types.StringType
+	case reflect.Bool:
+		return types.BoolType
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return types.IntType
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Byte slices are encoded as base64 strings
+			return types.StringType
+		}
+		return types.NewListType(s.declType(t.Elem()))
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return types.DynType
+		}
+		return types.NewMapType(types.StringType, s.declType(t.Elem()))
+	case reflect.Struct:
+		if t.Name() == "" {
+			return types.DynType
+		}
+		return s.declObject(t)
+	default:
+		// Floats are left dynamic, since integral values are converted to
+		// ints at runtime, see normalizeNumbers
+		return types.DynType
+	}
+}
+
+// declObject declares the object type of the struct t.
+func (s *schema) declObject(t reflect.Type) *types.Type {
+	name := objectTypeName(t)
+	objectType := types.NewObjectType(name)
+	if _, found := s.objects[name]; found {
+		return objectType
+	}
+
+	// Register the object before its fields so that recursive types terminate
+	object := &schemaObject{fields: map[string]*types.Type{}}
+	s.objects[name] = object
+	for _, field := range jsonFields(t) {
+		object.names = append(object.names, field.name)
+		object.fields[field.name] = s.declType(field.typ)
+	}
+	return objectType
+}
+
+// objectTypeName names the object type of a struct after the last two
+// elements of its package path, e.g. "pipeline.v1.PipelineRun" or
+// "meta.v1.ObjectMeta".
+func objectTypeName(t reflect.Type) string {
+	elements := strings.Split(t.PkgPath(), "/")
+	if len(elements) > 2 {
+		elements = elements[len(elements)-2:]
+	}
+	return strings.Join(append(elements, t.Name()), ".")
+}
+
+// jsonField is a field of the JSON encoding of a struct.
+type jsonField struct {
+	name string
+	typ  reflect.Type
+}
+
+// jsonFields lists the fields of the JSON encoding of the struct t. Fields
+// of embedded structs without a json name are inlined; a field of the outer
+// struct hides an inlined field of the same name.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields, inlined []jsonField
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				inlined = append(inlined, jsonFields(embedded)...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, jsonField{name: name, typ: field.Type})
+	}
+
+	for _, field := range inlined {
+		if !containsField(fields, field.name) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// containsField reports whether fields has a field with the given name.
+func containsField(fields []jsonField, name string) bool {
+	for _, field := range fields {
+		if field.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaProvider serves the object types of a schema to the type checker and
+// delegates everything else to the environment's provider. The fields have
+// no accessors, so that the runtime, which holds maps, selects them as map
+// keys.
+type schemaProvider struct {
+	types.Provider
+	schema *schema
+}
+
+// withSchema declares the object types of s in the environment.
+func withSchema(s *schema) cel.EnvOption {
+	return func(env *cel.Env) (*cel.Env, error) {
+		return cel.CustomTypeProvider(&schemaProvider{Provider: env.CELTypeProvider(), schema: s})(env)
+	}
+}
+
+// FindStructType implements types.Provider.
+func (p *schemaProvider) FindStructType(structType string) (*types.Type, bool) {
+	if _, found := p.schema.objects[structType]; found {
+		return types.NewTypeTypeWithParam(types.NewObjectType(structType)), true
+	}
+	return p.Provider.FindStructType(structType)
+}
+
+// FindStructFieldNames implements types.Provider.
+func (p *schemaProvider) FindStructFieldNames(structType string) ([]string, bool) {
+	if object, found := p.schema.objects[structType]; found {
+		return object.names, true
+	}
+	return p.Provider.FindStructFieldNames(structType)
+}
+
+// FindStructFieldType implements types.Provider.
+func (p *schemaProvider) FindStructFieldType(structType, fieldName string) (*types.FieldType, bool) {
+	object, found := p.schema.objects[structType]
+	if !found {
+		return p.Provider.FindStructFieldType(structType, fieldName)
+	}
+	fieldType, found := object.fields[fieldName]
+	if !found {
+		return nil, false
+	}
+	return &types.FieldType{Type: fieldType}, true
+}
+
+// NewValue implements types.Provider. Schema objects are read-only views of
+// the PipelineRun and cannot be created by expressions.
+func (p *schemaProvider) NewValue(structType string, fields map[string]ref.Val) ref.Val {
+	if _, found := p.schema.objects[structType]; found {
+		return types.NewErr("type %s cannot be created", structType)
+	}
+	return p.Provider.NewValue(structType, fields)
+}
+
+// normalizeNumbers converts the json.Number values of a decoded JSON
+// document into int64 when they are integral and float64 otherwise, so that
+// values of fields declared as int are ints at runtime.
+func normalizeNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return value
+}
@@ -0,0 +1,102 @@
+package cel
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPipelineRunSchema_Compile(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		errMsg     string
+	}{
+		{
+			name:       "metadata fields",
+			expression: `annotation("name", pipelineRun.metadata.name + "/" + pipelineRun.metadata.namespace)`,
+		},
+		{
+			name:       "inlined type meta",
+			expression: `annotation("kind", pipelineRun.apiVersion + "/" + pipelineRun.kind)`,
+		},
+		{
+			name:       "inlined status fields",
+			expression: `pipelineRun.status.conditions.exists(c, c.type == "Succeeded") || has(pipelineRun.status.pipelineSpec) ? [label("started", "true")] : []`,
+		},
+		{
+			name:       "param values are dynamic",
+			expression: `pipelineRun.spec.params.filter(p, p.name == "build-platforms")[0].value.map(p, resource(replace(p, "/", "-"), 1))`,
+		},
+		{
+			name:       "durations are strings",
+			expression: `has(pipelineRun.spec.timeouts) ? [annotation("timeout", pipelineRun.spec.timeouts.pipeline)] : []`,
+		},
+		{
+			name:       "unknown field",
+			expression: `annotation("key", pipelineRun.doesNotExist)`,
+			errMsg:     "undefined field 'doesNotExist'",
+		},
+		{
+			name:       "typo in a nested field",
+			expression: `label("team", pipelineRun.metdata.labels["team"])`,
+			errMsg:     "undefined field 'metdata'",
+		},
+		{
+			name:       "unknown field of a list element",
+			expression: `pipelineRun.spec.params.exists(p, p.nmae == "revision") ? [label("revision", "set")] : []`,
+			errMsg:     "undefined field 'nmae'",
+		},
+		{
+			name:       "type mismatch",
+			expression: `pipelineRun.metadata.name == 1 ? [label("one", "true")] : []`,
+			errMsg:     "found no matching overload for '_==_' applied to '(string, int)'",
+		},
+		{
+			name:       "string used as a list",
+			expression: `pipelineRun.metadata.namespace.map(n, label("namespace", n))`,
+			errMsg:     "expression of type 'string' cannot be range of a comprehension",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := CompileCELPrograms([]string{tt.expression})
+			if tt.errMsg == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+			}
+		})
+	}
+}
+
+func TestPipelineRunSchema_Evaluate(t *testing.T) {
+	g := NewWithT(t)
+
+	programs, err := CompileCELPrograms([]string{
+		`resource("cpu", pipelineRun.spec.pipelineSpec.tasks[0].retries + 1)`,
+		`annotation("timeout", pipelineRun.spec.timeouts.pipeline)`,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	pipelineRun := newRulesPipelineRun("tenant")
+	pipelineRun.Spec.PipelineRef = nil
+	pipelineRun.Spec.PipelineSpec = &tekv1.PipelineSpec{
+		Tasks: []tekv1.PipelineTask{{Name: "build", Retries: 2}},
+	}
+	pipelineRun.Spec.Timeouts = &tekv1.TimeoutFields{Pipeline: &metav1.Duration{Duration: time.Hour}}
+
+	mutations, err := programs[0].Evaluate(pipelineRun)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mutations).To(ConsistOf(&MutationRequest{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-cpu", Value: "3"}))
+
+	mutations, err = programs[1].Evaluate(pipelineRun)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mutations).To(ConsistOf(&MutationRequest{Type: MutationTypeAnnotation, Key: "timeout", Value: "1h0m0s"}))
+}
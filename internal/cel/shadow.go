@@ -0,0 +1,125 @@
+package cel
+
+import (
+	"context"
+	"fmt"
+
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// shadowResult holds the mutations of a shadow rule, which are compared with
+// the live result instead of being applied.
+type shadowResult struct {
+	rule      string
+	mutations []*MutationRequest
+}
+
+// evaluateShadow runs the shadow programs. Shadow rules run after the live
+// rules and never fail the admission: their errors, including timeouts, are
+// logged and counted like any other rule failure, and the rule is left out
+// of the comparison.
+//
+// Parameters:
+//   - ctx: The context of the evaluation, carrying the logger and the deadline
+//   - vars: The activation shared with the live rules
+//
+// Returns:
+//   - []shadowResult: The mutations of every shadow rule that evaluated
+func (m *CELMutator) evaluateShadow(ctx context.Context, vars map[string]interface{}) []shadowResult {
+	log := logf.FromContext(ctx)
+
+	var results []shadowResult
+	for _, program := range m.programs {
+		if !program.shadow {
+			continue
+		}
+		if ctx.Err() != nil {
+			log.Error(program.evaluationError(ctx, ctx.Err()), "Shadow CEL rule failed", "rule", program.GetName())
+			continue
+		}
+		mutations, err := program.evaluate(ctx, vars)
+		if err != nil {
+			mutations, err = program.handleError(ctx, vars, err)
+			if err != nil {
+				log.Error(err, "Shadow CEL rule failed", "rule", program.GetName())
+				continue
+			}
+		}
+		results = append(results, shadowResult{rule: program.GetName(), mutations: mutations})
+	}
+	return results
+}
+
+// compareShadow compares the mutations of every shadow rule with the
+// PipelineRun mutated by the live rules, then logs and counts the outcome.
+// A shadow rule matches when applying its mutations would not change any
+// label or annotation set by the live rules.
+func (m *CELMutator) compareShadow(ctx context.Context, pipelineRun *tekv1.PipelineRun, results []shadowResult) {
+	log := logf.FromContext(ctx)
+
+	for _, result := range results {
+		differences := m.shadowDifferences(pipelineRun, result.mutations)
+		matched := len(differences) == 0
+		RecordShadowEvaluation(result.rule, matched)
+		log.Info("Evaluated shadow CEL rule", "rule", result.rule, "matched", matched,
+			"mutations", len(result.mutations), "differences", differences)
+	}
+}
+
+// shadowDifferences describes every mutation of a shadow rule whose result
+// differs from the live PipelineRun. Resource values of the rule are
+// aggregated per key before they are compared.
+func (m *CELMutator) shadowDifferences(pipelineRun *tekv1.PipelineRun, mutations []*MutationRequest) []string {
+	var differences []string
+	resources := map[string]resource.Quantity{}
+	var resourceKeys []string
+	for _, mutation := range mutations {
+		var live string
+		var exists bool
+		switch mutation.Type {
+		case MutationTypeLabel, MutationTypeQueue, MutationTypeRemoveLabel:
+			live, exists = pipelineRun.Labels[mutation.Key]
+		case MutationTypeAnnotation, MutationTypeRemoveAnnotation:
+			live, exists = pipelineRun.Annotations[mutation.Key]
+		case MutationTypeResource:
+			value, err := resource.ParseQuantity(mutation.Value)
+			if err != nil {
+				differences = append(differences, fmt.Sprintf("resource %q: invalid shadow value %q", mutation.Key, mutation.Value))
+				continue
+			}
+			current, seen := resources[mutation.Key]
+			if !seen {
+				resources[mutation.Key] = value
+				resourceKeys = append(resourceKeys, mutation.Key)
+				continue
+			}
+			resources[mutation.Key] = aggregate(m.aggregationOf(mutation), current, value)
+			continue
+		}
+
+		target, _ := targetOf(mutation)
+		switch {
+		case mutation.Type.IsRemoval() && exists:
+			differences = append(differences, fmt.Sprintf("%s: shadow removes it, live %q", target, live))
+		case !mutation.Type.IsRemoval() && !exists:
+			differences = append(differences, fmt.Sprintf("%s: shadow %q, live unset", target, mutation.Value))
+		case !mutation.Type.IsRemoval() && live != mutation.Value:
+			differences = append(differences, fmt.Sprintf("%s: shadow %q, live %q", target, mutation.Value, live))
+		}
+	}
+
+	for _, key := range resourceKeys {
+		shadow := resources[key]
+		live, exists := pipelineRun.Annotations[key]
+		if !exists {
+			differences = append(differences, fmt.Sprintf("resource %q: shadow %q, live unset", key, formatQuantity(shadow)))
+			continue
+		}
+		if liveQuantity, err := resource.ParseQuantity(live); err != nil || liveQuantity.Cmp(shadow) != 0 {
+			differences = append(differences, fmt.Sprintf("resource %q: shadow %q, live %q", key, formatQuantity(shadow), live))
+		}
+	}
+	return differences
+}
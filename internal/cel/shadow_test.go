@@ -0,0 +1,156 @@
+package cel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCELMutator_Mutate_Shadow(t *testing.T) {
+	live := []config.Rule{
+		{Name: "live-priority", Mutations: `[priority("konflux-low"), annotation("owner", "team-a"), resource("cpu", 2)]`},
+	}
+
+	tests := []struct {
+		name    string
+		shadow  config.Rule
+		matched bool
+	}{
+		{
+			name:    "same output",
+			shadow:  config.Rule{Name: "shadow-same", Mutations: `[priority("konflux-low"), resource("cpu", 1), resource("cpu", 1)]`},
+			matched: true,
+		},
+		{
+			name:    "different label value",
+			shadow:  config.Rule{Name: "shadow-priority", Mutations: `priority("konflux-high")`},
+			matched: false,
+		},
+		{
+			name:    "unset label",
+			shadow:  config.Rule{Name: "shadow-queue", Mutations: `queue("release")`},
+			matched: false,
+		},
+		{
+			name:    "removal of a live annotation",
+			shadow:  config.Rule{Name: "shadow-removal", Mutations: `removeAnnotation("owner")`},
+			matched: false,
+		},
+		{
+			name:    "removal of an unset annotation",
+			shadow:  config.Rule{Name: "shadow-removal-unset", Mutations: `removeAnnotation("team")`},
+			matched: true,
+		},
+		{
+			name:    "different resource quantity",
+			shadow:  config.Rule{Name: "shadow-resource", Mutations: `resource("cpu", "2500m")`},
+			matched: false,
+		},
+		{
+			name:    "equal resource quantity",
+			shadow:  config.Rule{Name: "shadow-resource-equal", Mutations: `resource("cpu", "2000m")`},
+			matched: true,
+		},
+		{
+			name:    "when condition does not match",
+			shadow:  config.Rule{Name: "shadow-when", When: `plrNamespace == "release"`, Mutations: `priority("konflux-high")`},
+			matched: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			tt.shadow.Shadow = true
+			programs, err := CompileConfig(config.CEL{Rules: append([]config.Rule{tt.shadow}, live...)})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			matches := testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues(tt.shadow.Name, "true"))
+			mismatches := testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues(tt.shadow.Name, "false"))
+
+			pipelineRun := newRulesPipelineRun("tenant")
+			err = NewCELMutator(programs).Mutate(context.Background(), pipelineRun, Inputs{})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(pipelineRun.Labels).To(Equal(map[string]string{"kueue.x-k8s.io/priority-class": "konflux-low"}))
+			g.Expect(pipelineRun.Annotations).To(Equal(map[string]string{
+				"owner":                             "team-a",
+				"kueue.konflux-ci.dev/requests-cpu": "2",
+			}))
+
+			if tt.matched {
+				g.Expect(testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues(tt.shadow.Name, "true"))).To(Equal(matches + 1))
+				g.Expect(testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues(tt.shadow.Name, "false"))).To(Equal(mismatches))
+			} else {
+				g.Expect(testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues(tt.shadow.Name, "true"))).To(Equal(matches))
+				g.Expect(testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues(tt.shadow.Name, "false"))).To(Equal(mismatches + 1))
+			}
+		})
+	}
+}
+
+func TestCELMutator_Mutate_ShadowFailure(t *testing.T) {
+	g := NewWithT(t)
+
+	programs, err := CompileConfig(config.CEL{Rules: []config.Rule{
+		{Name: "shadow-failure", Mutations: `label("revision", param("missing"))`, Shadow: true},
+		{Name: "env", Mutations: `label("env", "test")`},
+	}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	failures := testutil.ToFloat64(celEvaluationsTotal.WithLabelValues("failure", "shadow-failure"))
+	matches := testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues("shadow-failure", "true"))
+	mismatches := testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues("shadow-failure", "false"))
+
+	pipelineRun := newRulesPipelineRun("tenant")
+	err = NewCELMutator(programs).Mutate(context.Background(), pipelineRun, Inputs{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pipelineRun.Labels).To(Equal(map[string]string{"env": "test"}))
+
+	g.Expect(testutil.ToFloat64(celEvaluationsTotal.WithLabelValues("failure", "shadow-failure"))).To(Equal(failures + 1))
+	g.Expect(testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues("shadow-failure", "true"))).To(Equal(matches))
+	g.Expect(testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues("shadow-failure", "false"))).To(Equal(mismatches))
+}
+
+func TestCELMutator_Mutate_ShadowDefaultedQueue(t *testing.T) {
+	tests := []struct {
+		name    string
+		queue   string
+		matched bool
+	}{
+		{name: "defaulted queue matches", queue: "release", matched: true},
+		{name: "defaulted queue differs", queue: "pipelines-queue", matched: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileConfig(config.CEL{Rules: []config.Rule{
+				{Name: "shadow-defaulted-queue", Mutations: `queue("release")`, Shadow: true},
+				{Name: "env", Mutations: `label("env", "test")`},
+			}})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			matches := testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues("shadow-defaulted-queue", "true"))
+			mismatches := testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues("shadow-defaulted-queue", "false"))
+
+			// The webhook defaults the queue label before the mutators run
+			pipelineRun := newRulesPipelineRun("tenant")
+			pipelineRun.Labels = map[string]string{"kueue.x-k8s.io/queue-name": tt.queue}
+			err = NewCELMutator(programs).Mutate(context.Background(), pipelineRun, Inputs{})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			if tt.matched {
+				g.Expect(testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues("shadow-defaulted-queue", "true"))).To(Equal(matches + 1))
+				g.Expect(testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues("shadow-defaulted-queue", "false"))).To(Equal(mismatches))
+			} else {
+				g.Expect(testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues("shadow-defaulted-queue", "true"))).To(Equal(matches))
+				g.Expect(testutil.ToFloat64(celShadowEvaluationsTotal.WithLabelValues("shadow-defaulted-queue", "false"))).To(Equal(mismatches + 1))
+			}
+		})
+	}
+}
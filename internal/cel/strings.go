@@ -0,0 +1,217 @@
+package cel
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// invalidLabelValueChars matches the characters that cannot appear in a
+// label value.
+var invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// sanitizedHashLength is the length of the hash suffix that
+// sanitizeLabelValue appends to values it shortens.
+const sanitizedHashLength = 8
+
+// createStringFunctions creates the regex and string helpers. Patterns use
+// the Go RE2 syntax; a pattern given as a literal is checked at compile
+// time.
+func createStringFunctions() []cel.EnvOption {
+	return []cel.EnvOption{
+		createPatternMacro("regexReplace", 3),
+		cel.Function("regexReplace",
+			cel.Overload("regexReplace_string_string_string_to_string",
+				[]*cel.Type{cel.StringType, cel.StringType, cel.StringType},
+				cel.StringType,
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					source, sourceOk := args[0].Value().(string)
+					pattern, patternOk := args[1].Value().(string)
+					replacement, replacementOk := args[2].Value().(string)
+					if !sourceOk || !patternOk || !replacementOk {
+						return types.NewErr("regexReplace function requires string arguments")
+					}
+					re, err := regexp.Compile(pattern)
+					if err != nil {
+						return types.NewErr("regexReplace: invalid pattern %q: %v", pattern, err)
+					}
+					return types.String(re.ReplaceAllString(source, replacement))
+				}),
+			),
+		),
+		createPatternMacro("regexFind", 2),
+		cel.Function("regexFind",
+			cel.Overload("regexFind_string_string_to_list",
+				[]*cel.Type{cel.StringType, cel.StringType},
+				cel.ListType(cel.StringType),
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					source, sourceOk := lhs.Value().(string)
+					pattern, patternOk := rhs.Value().(string)
+					if !sourceOk || !patternOk {
+						return types.NewErr("regexFind function requires string arguments")
+					}
+					re, err := regexp.Compile(pattern)
+					if err != nil {
+						return types.NewErr("regexFind: invalid pattern %q: %v", pattern, err)
+					}
+					match := re.FindStringSubmatch(source)
+					if match == nil {
+						match = []string{}
+					}
+					return types.NewStringList(types.DefaultTypeAdapter, match)
+				}),
+			),
+		),
+		cel.Function("split",
+			cel.Overload("split_string_string_to_list",
+				[]*cel.Type{cel.StringType, cel.StringType},
+				cel.ListType(cel.StringType),
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					source, sourceOk := lhs.Value().(string)
+					separator, separatorOk := rhs.Value().(string)
+					if !sourceOk || !separatorOk {
+						return types.NewErr("split function requires string arguments")
+					}
+					return types.NewStringList(types.DefaultTypeAdapter, strings.Split(source, separator))
+				}),
+			),
+		),
+		cel.Function("join",
+			cel.Overload("join_list_string_to_string",
+				[]*cel.Type{cel.ListType(cel.StringType), cel.StringType},
+				cel.StringType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					list, listOk := lhs.(traits.Lister)
+					separator, separatorOk := rhs.Value().(string)
+					if !listOk || !separatorOk {
+						return types.NewErr("join function requires a list of strings and a string")
+					}
+					var elements []string
+					for it := list.Iterator(); it.HasNext() == types.True; {
+						element, ok := it.Next().Value().(string)
+						if !ok {
+							return types.NewErr("join function requires a list of strings")
+						}
+						elements = append(elements, element)
+					}
+					return types.String(strings.Join(elements, separator))
+				}),
+			),
+		),
+		createUnaryStringFunction("lower", strings.ToLower),
+		createUnaryStringFunction("upper", strings.ToUpper),
+		cel.Function("trimPrefix",
+			cel.Overload("trimPrefix_string_string_to_string",
+				[]*cel.Type{cel.StringType, cel.StringType},
+				cel.StringType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					source, sourceOk := lhs.Value().(string)
+					prefix, prefixOk := rhs.Value().(string)
+					if !sourceOk || !prefixOk {
+						return types.NewErr("trimPrefix function requires string arguments")
+					}
+					return types.String(strings.TrimPrefix(source, prefix))
+				}),
+			),
+		),
+		cel.Function("truncate",
+			cel.Overload("truncate_string_int_to_string",
+				[]*cel.Type{cel.StringType, cel.IntType},
+				cel.StringType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					source, sourceOk := lhs.Value().(string)
+					length, lengthOk := rhs.Value().(int64)
+					if !sourceOk || !lengthOk {
+						return types.NewErr("truncate function requires a string and an int")
+					}
+					if length < 0 {
+						return types.NewErr("truncate length cannot be negative, got %d", length)
+					}
+					return types.String(truncate(source, int(length)))
+				}),
+			),
+		),
+		createUnaryStringFunction("sanitizeLabelValue", sanitizeLabelValue),
+	}
+}
+
+// createUnaryStringFunction creates a CEL function mapping a string to a
+// string with fn.
+func createUnaryStringFunction(name string, fn func(string) string) cel.EnvOption {
+	return cel.Function(name,
+		cel.Overload(name+"_string_to_string",
+			[]*cel.Type{cel.StringType},
+			cel.StringType,
+			cel.UnaryBinding(func(val ref.Val) ref.Val {
+				value, ok := val.Value().(string)
+				if !ok {
+					return types.NewErr("%s function requires a string argument", name)
+				}
+				return types.String(fn(value))
+			}),
+		),
+	)
+}
+
+// createPatternMacro creates a macro rejecting calls to function whose
+// pattern, its second argument, is a literal that is not a valid regular
+// expression. The call itself is left unchanged.
+func createPatternMacro(function string, argCount int) cel.EnvOption {
+	return cel.Macros(cel.GlobalMacro(function, argCount,
+		func(eh cel.MacroExprFactory, _ ast.Expr, args []ast.Expr) (ast.Expr, *common.Error) {
+			if args[1].Kind() != ast.LiteralKind {
+				return nil, nil
+			}
+			pattern, ok := args[1].AsLiteral().Value().(string)
+			if !ok {
+				return nil, nil
+			}
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, eh.NewError(args[1].ID(), fmt.Sprintf("invalid pattern %q: %v", pattern, err))
+			}
+			return nil, nil
+		},
+	))
+}
+
+// truncate returns the first length characters of value.
+func truncate(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) <= length {
+		return value
+	}
+	return string(runes[:length])
+}
+
+// sanitizeLabelValue turns value into a valid label value: characters other
+// than alphanumerics, '-', '_' and '.' become '-', and the result is trimmed
+// to start and end with an alphanumeric. A result longer than 63 characters
+// is shortened and suffixed with a hash of value, so that distinct long
+// values, such as branch names sharing a prefix, stay distinct.
+func sanitizeLabelValue(value string) string {
+	sanitized := trimToAlphanumeric(invalidLabelValueChars.ReplaceAllString(value, "-"))
+	if len(sanitized) <= validation.LabelValueMaxLength {
+		return sanitized
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(value))
+	prefix := trimToAlphanumeric(sanitized[:validation.LabelValueMaxLength-sanitizedHashLength-1])
+	return fmt.Sprintf("%s-%0*x", prefix, sanitizedHashLength, hash.Sum32())
+}
+
+// trimToAlphanumeric trims the leading and trailing characters of value that
+// are not ASCII alphanumerics.
+func trimToAlphanumeric(value string) string {
+	return strings.TrimFunc(value, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	})
+}
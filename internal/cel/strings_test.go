@@ -0,0 +1,154 @@
+package cel
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestStringFunctions(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   string
+		errMsg     string
+	}{
+		{
+			name:       "regexReplace",
+			expression: `regexReplace("feature/JIRA-123-login", "^feature/([A-Z]+-[0-9]+).*$", "$1")`,
+			expected:   "JIRA-123",
+		},
+		{
+			name:       "regexReplace with a computed pattern",
+			expression: `regexReplace(plrNamespace, truncate(plrNamespace, 3) + ".*", "x")`,
+			expected:   "x",
+		},
+		{
+			name:       "regexFind",
+			expression: `join(regexFind("release-1.2.3", "([0-9]+)\\.([0-9]+)"), ",")`,
+			expected:   "1.2,1,2",
+		},
+		{
+			name:       "regexFind without a match",
+			expression: `string(regexFind("main", "[0-9]+").size())`,
+			expected:   "0",
+		},
+		{
+			name:       "split",
+			expression: `split("konflux-ci/build-service", "/")[1]`,
+			expected:   "build-service",
+		},
+		{
+			name:       "join",
+			expression: `join(["linux", "amd64"], "-")`,
+			expected:   "linux-amd64",
+		},
+		{
+			name:       "lower",
+			expression: `lower("Feature-Branch")`,
+			expected:   "feature-branch",
+		},
+		{
+			name:       "upper",
+			expression: `upper("Feature-Branch")`,
+			expected:   "FEATURE-BRANCH",
+		},
+		{
+			name:       "trimPrefix",
+			expression: `trimPrefix("refs/heads/main", "refs/heads/")`,
+			expected:   "main",
+		},
+		{
+			name:       "truncate",
+			expression: `truncate("build-service", 5)`,
+			expected:   "build",
+		},
+		{
+			name:       "truncate a short string",
+			expression: `truncate("build", 63)`,
+			expected:   "build",
+		},
+		{
+			name:       "sanitizeLabelValue",
+			expression: `sanitizeLabelValue("feature/login page!")`,
+			expected:   "feature-login-page",
+		},
+		{
+			name:       "negative truncate length",
+			expression: `truncate("build", -1)`,
+			errMsg:     "truncate length cannot be negative, got -1",
+		},
+		{
+			name:       "invalid computed pattern",
+			expression: `regexReplace("main", plrNamespace + "(", "")`,
+			errMsg:     `regexReplace: invalid pattern "tenant("`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileCELPrograms([]string{`annotation("result", ` + tt.expression + `)`})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			mutations, err := programs[0].Evaluate(newRulesPipelineRun("tenant"))
+			if tt.errMsg != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(mutations).To(HaveLen(1))
+			g.Expect(mutations[0].Value).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestStringFunctions_InvalidPattern(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := CompileCELPrograms([]string{`annotation("ticket", regexReplace(plrNamespace, "[a-z", ""))`})
+	g.Expect(err).To(MatchError(ContainSubstring(`invalid pattern "[a-z"`)))
+
+	_, err = CompileCELPrograms([]string{`annotation("ticket", regexFind(plrNamespace, "(")[0])`})
+	g.Expect(err).To(MatchError(ContainSubstring(`invalid pattern "("`)))
+}
+
+func TestSanitizeLabelValue(t *testing.T) {
+	long := "feature/" + strings.Repeat("a", 70)
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "valid value", value: "main", expected: "main"},
+		{name: "empty value", value: "", expected: ""},
+		{name: "invalid characters", value: "feature/login page", expected: "feature-login-page"},
+		{name: "leading and trailing separators", value: "-_release/", expected: "release"},
+		{name: "only invalid characters", value: "///", expected: ""},
+		{name: "long value", value: long, expected: "feature-" + strings.Repeat("a", 46) + "-be6f20b8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			sanitized := sanitizeLabelValue(tt.value)
+			g.Expect(sanitized).To(Equal(tt.expected))
+			g.Expect(validation.IsValidLabelValue(sanitized)).To(BeEmpty())
+		})
+	}
+}
+
+func TestSanitizeLabelValue_DistinctLongValues(t *testing.T) {
+	g := NewWithT(t)
+
+	prefix := "renovate/" + strings.Repeat("konflux-ci-", 6)
+	first := sanitizeLabelValue(prefix + "build-service")
+	second := sanitizeLabelValue(prefix + "release-service")
+	g.Expect(first).To(HaveLen(validation.LabelValueMaxLength))
+	g.Expect(second).To(HaveLen(validation.LabelValueMaxLength))
+	g.Expect(first).NotTo(Equal(second))
+}
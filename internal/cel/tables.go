@@ -0,0 +1,154 @@
+package cel
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+)
+
+// CompileOption configures optional inputs of CompileConfig.
+type CompileOption func(*compileOptions)
+
+// compileOptions holds the inputs set by CompileOptions.
+type compileOptions struct {
+	tables   map[string]config.Table
+	profiles map[string]config.Profile
+}
+
+// WithTables makes lookup tables available to lookup() and lookupOr(). The
+// tables are validated by CompileConfig.
+func WithTables(tables map[string]config.Table) CompileOption {
+	return func(o *compileOptions) {
+		o.tables = tables
+	}
+}
+
+// ValidateTables checks that every table has a name and that all values of
+// a table have the same type, so that expressions can rely on it. The
+// webhook also calls it for configurations without rules, which never reach
+// CompileConfig.
+func ValidateTables(tables map[string]config.Table) error {
+	for _, name := range sortedKeys(tables) {
+		if name == "" {
+			return fmt.Errorf("tables: table name cannot be empty")
+		}
+		var tableType config.TableValueType
+		for _, key := range sortedKeys(tables[name]) {
+			value := tables[name][key]
+			if tableType == "" {
+				tableType = value.Type
+				continue
+			}
+			if value.Type != tableType {
+				return fmt.Errorf("tables.%s.%s: table %q mixes %s and %s values: all values of a table must have the same type",
+					name, key, name, tableType, value.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in order, for deterministic errors.
+func sortedKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}
+
+// createTableFunctions creates the lookup() and lookupOr() functions over
+// the configured tables. Both return dyn, typed after the values of the
+// table: string, int or list<string>. A table name given as a literal is
+// checked at compile time.
+func createTableFunctions(tables map[string]config.Table) []cel.EnvOption {
+	return []cel.EnvOption{
+		createNameMacro("lookup", 2, "table", tables),
+		createNameMacro("lookupOr", 3, "table", tables),
+		cel.Function("lookup",
+			cel.Overload("lookup_string_string_to_dyn",
+				[]*cel.Type{cel.StringType, cel.StringType},
+				cel.DynType,
+				cel.BinaryBinding(func(tableVal, keyVal ref.Val) ref.Val {
+					table, key, errVal := tableArgs("lookup", tables, tableVal, keyVal)
+					if errVal != nil {
+						return errVal
+					}
+					value, found := table[key]
+					if !found {
+						return types.NewErr("lookup: key %q not found in table %q", key, tableVal.Value())
+					}
+					return tableValueToVal(value)
+				}),
+			),
+		),
+		cel.Function("lookupOr",
+			cel.Overload("lookupOr_string_string_dyn_to_dyn",
+				[]*cel.Type{cel.StringType, cel.StringType, cel.DynType},
+				cel.DynType,
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					table, key, errVal := tableArgs("lookupOr", tables, args[0], args[1])
+					if errVal != nil {
+						return errVal
+					}
+					value, found := table[key]
+					if !found {
+						return args[2]
+					}
+					return tableValueToVal(value)
+				}),
+			),
+		),
+	}
+}
+
+// tableArgs resolves the table and key arguments of a lookup function.
+func tableArgs(function string, tables map[string]config.Table, tableVal, keyVal ref.Val) (config.Table, string, ref.Val) {
+	name, nameOk := tableVal.Value().(string)
+	key, keyOk := keyVal.Value().(string)
+	if !nameOk || !keyOk {
+		return nil, "", types.NewErr("%s function requires string arguments", function)
+	}
+	table, found := tables[name]
+	if !found {
+		return nil, "", types.NewErr("%s: unknown table %q", function, name)
+	}
+	return table, key, nil
+}
+
+// tableValueToVal converts a table value into a CEL value.
+func tableValueToVal(value config.TableValue) ref.Val {
+	switch value.Type {
+	case config.TableValueTypeInt:
+		return types.Int(value.IntVal)
+	case config.TableValueTypeList:
+		return types.NewStringList(types.DefaultTypeAdapter, value.ListVal)
+	default:
+		return types.String(value.StringVal)
+	}
+}
+
+// createNameMacro creates a macro rejecting calls to function whose first
+// argument is a literal that is not a key of names, such as an unknown
+// table. kind names the keys in the error. The call itself is left
+// unchanged.
+func createNameMacro[V any](function string, argCount int, kind string, names map[string]V) cel.EnvOption {
+	return cel.Macros(cel.GlobalMacro(function, argCount,
+		func(eh cel.MacroExprFactory, _ ast.Expr, args []ast.Expr) (ast.Expr, *common.Error) {
+			if args[0].Kind() != ast.LiteralKind {
+				return nil, nil
+			}
+			name, ok := args[0].AsLiteral().Value().(string)
+			if !ok {
+				return nil, nil
+			}
+			if _, found := names[name]; !found {
+				return nil, eh.NewError(args[0].ID(), fmt.Sprintf("unknown %s %q, must be one of: %v", kind, name, sortedKeys(names)))
+			}
+			return nil, nil
+		},
+	))
+}
@@ -0,0 +1,136 @@
+package cel
+
+import (
+	"testing"
+
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	. "github.com/onsi/gomega"
+)
+
+var testTables = map[string]config.Table{
+	"tiers": {
+		"tenant":  {Type: config.TableValueTypeString, StringVal: "gold"},
+		"release": {Type: config.TableValueTypeString, StringVal: "platinum"},
+	},
+	"weights": {
+		"tenant": {Type: config.TableValueTypeInt, IntVal: 3},
+	},
+	"platforms": {
+		"tenant": {Type: config.TableValueTypeList, ListVal: []string{"linux/amd64", "linux/arm64"}},
+	},
+}
+
+func TestTableFunctions(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   []MutationRequest
+		errMsg     string
+	}{
+		{
+			name:       "string value",
+			expression: `label("tier", lookup("tiers", plrNamespace))`,
+			expected:   []MutationRequest{{Type: MutationTypeLabel, Key: "tier", Value: "gold"}},
+		},
+		{
+			name:       "int value",
+			expression: `resource("weight", lookup("weights", plrNamespace) * 2)`,
+			expected:   []MutationRequest{{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-weight", Value: "6"}},
+		},
+		{
+			name:       "list value",
+			expression: `lookup("platforms", plrNamespace).map(p, resource(replace(p, "/", "-"), 1))`,
+			expected: []MutationRequest{
+				{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-linux-amd64", Value: "1"},
+				{Type: MutationTypeResource, Key: "kueue.konflux-ci.dev/requests-linux-arm64", Value: "1"},
+			},
+		},
+		{
+			name:       "lookupOr with a known key",
+			expression: `label("tier", lookupOr("tiers", plrNamespace, "bronze"))`,
+			expected:   []MutationRequest{{Type: MutationTypeLabel, Key: "tier", Value: "gold"}},
+		},
+		{
+			name:       "lookupOr with an unknown key",
+			expression: `label("tier", lookupOr("tiers", "unknown", "bronze"))`,
+			expected:   []MutationRequest{{Type: MutationTypeLabel, Key: "tier", Value: "bronze"}},
+		},
+		{
+			name:       "lookup with an unknown key",
+			expression: `label("tier", lookup("tiers", "unknown"))`,
+			errMsg:     `lookup: key "unknown" not found in table "tiers"`,
+		},
+		{
+			name:       "table name computed at runtime",
+			expression: `label("tier", lookup(plrNamespace + "-tiers", "tenant"))`,
+			errMsg:     `lookup: unknown table "tenant-tiers"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileConfig(config.CEL{Expressions: []string{tt.expression}}, WithTables(testTables))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			mutations, err := programs[0].Evaluate(newRulesPipelineRun("tenant"))
+			if tt.errMsg != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(mutations).To(HaveLen(len(tt.expected)))
+			for i, expected := range tt.expected {
+				g.Expect(*mutations[i]).To(Equal(expected))
+			}
+		})
+	}
+}
+
+func TestCompileConfig_TableErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		tables     map[string]config.Table
+		expression string
+		errMsg     string
+	}{
+		{
+			name:       "unknown table",
+			tables:     testTables,
+			expression: `label("tier", lookup("tier", plrNamespace))`,
+			errMsg:     `unknown table "tier", must be one of: [platforms tiers weights]`,
+		},
+		{
+			name:       "unknown table in lookupOr",
+			expression: `label("tier", lookupOr("tiers", plrNamespace, "bronze"))`,
+			errMsg:     `unknown table "tiers", must be one of: []`,
+		},
+		{
+			name: "mixed value types",
+			tables: map[string]config.Table{
+				"tiers": {
+					"a": {Type: config.TableValueTypeString, StringVal: "gold"},
+					"b": {Type: config.TableValueTypeInt, IntVal: 1},
+				},
+			},
+			expression: `label("tier", lookup("tiers", plrNamespace))`,
+			errMsg:     `tables.tiers.b: table "tiers" mixes string and int values`,
+		},
+		{
+			name:       "empty table name",
+			tables:     map[string]config.Table{"": {}},
+			expression: `label("env", "test")`,
+			errMsg:     `tables: table name cannot be empty`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := CompileConfig(config.CEL{Expressions: []string{tt.expression}}, WithTables(tt.tables))
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package cel
+
+import (
+	"regexp"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+)
+
+// arrayParamReference matches a matrix param value that references a whole
+// array param, e.g. "$(params.platforms[*])".
+var arrayParamReference = regexp.MustCompile(`^\$\(params\.([^.\[\]()]+)\[\*\]\)$`)
+
+// createTaskFunctions creates the tasks(), finallyTasks(), hasTask(),
+// taskRefs() and matrixWidth() helpers, which inspect the tasks of the
+// embedded spec.pipelineSpec. A PipelineRun with a pipelineRef has no
+// embedded spec, so it has no tasks.
+func createTaskFunctions() []cel.EnvOption {
+	return []cel.EnvOption{
+		createContextMacro("tasks", 0),
+		cel.Function("tasks",
+			cel.Overload("tasks_context_to_list",
+				[]*cel.Type{cel.DynType},
+				cel.ListType(cel.StringType),
+				cel.UnaryBinding(func(val ref.Val) ref.Val {
+					spec, errVal := taskArgs("tasks", val)
+					if errVal != nil {
+						return errVal
+					}
+					return types.NewStringList(types.DefaultTypeAdapter, taskNames(spec.Tasks))
+				}),
+			),
+		),
+		createContextMacro("finallyTasks", 0),
+		cel.Function("finallyTasks",
+			cel.Overload("finallyTasks_context_to_list",
+				[]*cel.Type{cel.DynType},
+				cel.ListType(cel.StringType),
+				cel.UnaryBinding(func(val ref.Val) ref.Val {
+					spec, errVal := taskArgs("finallyTasks", val)
+					if errVal != nil {
+						return errVal
+					}
+					return types.NewStringList(types.DefaultTypeAdapter, taskNames(spec.Finally))
+				}),
+			),
+		),
+		createContextMacro("hasTask", 1),
+		cel.Function("hasTask",
+			cel.Overload("hasTask_context_string_to_bool",
+				[]*cel.Type{cel.DynType, cel.StringType},
+				cel.BoolType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					spec, errVal := taskArgs("hasTask", lhs)
+					if errVal != nil {
+						return errVal
+					}
+					name, ok := rhs.Value().(string)
+					if !ok {
+						return types.NewErr("hasTask function requires a string argument")
+					}
+					return types.Bool(findTask(spec, name) != nil)
+				}),
+			),
+		),
+		createContextMacro("taskRefs", 0),
+		cel.Function("taskRefs",
+			cel.Overload("taskRefs_context_to_list",
+				[]*cel.Type{cel.DynType},
+				cel.ListType(cel.MapType(cel.StringType, cel.StringType)),
+				cel.UnaryBinding(func(val ref.Val) ref.Val {
+					spec, errVal := taskArgs("taskRefs", val)
+					if errVal != nil {
+						return errVal
+					}
+					var refs []ref.Val
+					for _, task := range allTasks(spec) {
+						if task.TaskRef == nil {
+							continue
+						}
+						refs = append(refs, types.NewStringStringMap(types.DefaultTypeAdapter, taskRefToMap(task)))
+					}
+					return types.NewRefValList(types.DefaultTypeAdapter, refs)
+				}),
+			),
+		),
+		createContextMacro("matrixWidth", 1),
+		cel.Function("matrixWidth",
+			cel.Overload("matrixWidth_context_string_to_int",
+				[]*cel.Type{cel.DynType, cel.StringType},
+				cel.IntType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					spec, errVal := taskArgs("matrixWidth", lhs)
+					if errVal != nil {
+						return errVal
+					}
+					name, ok := rhs.Value().(string)
+					if !ok {
+						return types.NewErr("matrixWidth function requires a string argument")
+					}
+					task := findTask(spec, name)
+					if task == nil {
+						return types.Int(0)
+					}
+					ctx, _ := contextFromVal(lhs)
+					return types.Int(matrixWidth(task.Matrix, ctx.params))
+				}),
+			),
+		),
+	}
+}
+
+// taskArgs validates the context passed to the task helpers and returns the
+// embedded PipelineSpec, which is empty if the PipelineRun has none.
+func taskArgs(function string, val ref.Val) (*tekv1.PipelineSpec, ref.Val) {
+	ctx, ok := contextFromVal(val)
+	if !ok {
+		return nil, types.NewErr("%s function requires the evaluation context", function)
+	}
+	if ctx.pipelineRun.Spec.PipelineSpec == nil {
+		return &tekv1.PipelineSpec{}, nil
+	}
+	return ctx.pipelineRun.Spec.PipelineSpec, nil
+}
+
+// taskNames returns the names of tasks, in order.
+func taskNames(tasks []tekv1.PipelineTask) []string {
+	names := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		names = append(names, task.Name)
+	}
+	return names
+}
+
+// allTasks returns the tasks of spec followed by its finally tasks.
+func allTasks(spec *tekv1.PipelineSpec) []tekv1.PipelineTask {
+	return append(append([]tekv1.PipelineTask{}, spec.Tasks...), spec.Finally...)
+}
+
+// findTask returns the task or finally task of spec with the given name, or
+// nil if there is none.
+func findTask(spec *tekv1.PipelineSpec, name string) *tekv1.PipelineTask {
+	for _, task := range allTasks(spec) {
+		if task.Name == name {
+			return &task
+		}
+	}
+	return nil
+}
+
+// taskRefToMap describes the taskRef of task: the pipeline task name, the
+// name and kind of the referenced task, its resolver and, for the bundles
+// resolver, its bundle. For remote tasks the name is the "name" resolver
+// param.
+func taskRefToMap(task tekv1.PipelineTask) map[string]string {
+	taskRef := task.TaskRef
+	name := taskRef.Name
+	if name == "" {
+		name = resolverParam(taskRef.Params, "name")
+	}
+	bundle := ""
+	if taskRef.Resolver == bundlesResolver {
+		bundle = resolverParam(taskRef.Params, "bundle")
+	}
+	return map[string]string{
+		"task":     task.Name,
+		"name":     name,
+		"kind":     string(taskRef.Kind),
+		"resolver": string(taskRef.Resolver),
+		"bundle":   bundle,
+	}
+}
+
+// matrixWidth returns the number of TaskRuns that a task with the given
+// matrix fans out to, or 1 without a matrix. Matrix params referencing a
+// whole array param, such as "$(params.platforms[*])", are resolved against
+// params first; other references, e.g. to task results, are unknown at
+// admission and don't count.
+func matrixWidth(matrix *tekv1.Matrix, params map[string]tekv1.ParamValue) int {
+	if !matrix.HasParams() && !matrix.HasInclude() {
+		return 1
+	}
+	resolved := matrix.DeepCopy()
+	for i, param := range resolved.Params {
+		if param.Value.Type != tekv1.ParamTypeString {
+			continue
+		}
+		match := arrayParamReference.FindStringSubmatch(param.Value.StringVal)
+		if match == nil {
+			continue
+		}
+		if value, ok := params[match[1]]; ok && value.Type == tekv1.ParamTypeArray {
+			resolved.Params[i].Value = value
+		}
+	}
+	return resolved.CountCombinations()
+}
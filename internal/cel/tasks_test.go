@@ -0,0 +1,181 @@
+package cel
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+)
+
+func newTasksPipelineRun() *tekv1.PipelineRun {
+	pipelineRun := newRulesPipelineRun("tenant")
+	pipelineRun.Spec.PipelineRef = nil
+	pipelineRun.Spec.Params = []tekv1.Param{
+		{Name: "build-platforms", Value: *tekv1.NewStructuredValues("linux/amd64", "linux/arm64", "linux/s390x")},
+	}
+	pipelineRun.Spec.PipelineSpec = &tekv1.PipelineSpec{
+		Tasks: []tekv1.PipelineTask{
+			{
+				Name: "clone",
+				TaskRef: &tekv1.TaskRef{
+					ResolverRef: tekv1.ResolverRef{
+						Resolver: "bundles",
+						Params: tekv1.Params{
+							{Name: "name", Value: *tekv1.NewStructuredValues("git-clone")},
+							{Name: "bundle", Value: *tekv1.NewStructuredValues("quay.io/konflux-ci/task-git-clone:0.1")},
+							{Name: "kind", Value: *tekv1.NewStructuredValues("task")},
+						},
+					},
+				},
+			},
+			{
+				Name:    "build",
+				TaskRef: &tekv1.TaskRef{Name: "buildah", Kind: tekv1.NamespacedTaskKind},
+				Matrix: &tekv1.Matrix{
+					Params: tekv1.Params{
+						{Name: "PLATFORM", Value: *tekv1.NewStructuredValues("$(params.build-platforms[*])")},
+					},
+				},
+			},
+			{
+				Name:     "test",
+				TaskSpec: &tekv1.EmbeddedTask{},
+				Matrix: &tekv1.Matrix{
+					Params: tekv1.Params{
+						{Name: "suite", Value: *tekv1.NewStructuredValues("unit", "e2e")},
+						{Name: "arch", Value: *tekv1.NewStructuredValues("amd64", "arm64")},
+					},
+					Include: tekv1.IncludeParamsList{
+						{Name: "fips", Params: tekv1.Params{{Name: "arch", Value: *tekv1.NewStructuredValues("amd64-fips")}}},
+					},
+				},
+			},
+			{
+				Name:    "scan",
+				TaskRef: &tekv1.TaskRef{Name: "scan"},
+				Matrix: &tekv1.Matrix{
+					Params: tekv1.Params{
+						{Name: "image", Value: *tekv1.NewStructuredValues("$(tasks.build.results.images[*])")},
+					},
+				},
+			},
+		},
+		Finally: []tekv1.PipelineTask{
+			{Name: "notify", TaskRef: &tekv1.TaskRef{Name: "slack-notify"}},
+		},
+	}
+	return pipelineRun
+}
+
+func TestTaskFunctions(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   []string
+	}{
+		{
+			name:       "tasks",
+			expression: `tasks()`,
+			expected:   []string{"clone", "build", "test", "scan"},
+		},
+		{
+			name:       "finally tasks",
+			expression: `finallyTasks()`,
+			expected:   []string{"notify"},
+		},
+		{
+			name:       "has task",
+			expression: `[string(hasTask("build"))]`,
+			expected:   []string{"true"},
+		},
+		{
+			name:       "has finally task",
+			expression: `[string(hasTask("notify"))]`,
+			expected:   []string{"true"},
+		},
+		{
+			name:       "has unknown task",
+			expression: `[string(hasTask("deploy"))]`,
+			expected:   []string{"false"},
+		},
+		{
+			name:       "task refs by name",
+			expression: `taskRefs().filter(r, r.name == "buildah").map(r, r.task + "/" + r.kind)`,
+			expected:   []string{"build/Task"},
+		},
+		{
+			name:       "task refs by bundle",
+			expression: `taskRefs().filter(r, r.bundle.startsWith("quay.io/konflux-ci/")).map(r, r.task + "/" + r.name + "/" + r.resolver)`,
+			expected:   []string{"clone/git-clone/bundles"},
+		},
+		{
+			name:       "task refs include finally tasks and skip embedded tasks",
+			expression: `taskRefs().map(r, r.task)`,
+			expected:   []string{"clone", "build", "scan", "notify"},
+		},
+		{
+			name:       "matrix over an array param",
+			expression: `[string(matrixWidth("build"))]`,
+			expected:   []string{"3"},
+		},
+		{
+			name:       "matrix with include",
+			expression: `[string(matrixWidth("test"))]`,
+			expected:   []string{"5"},
+		},
+		{
+			name:       "matrix over a task result",
+			expression: `[string(matrixWidth("scan"))]`,
+			expected:   []string{"1"},
+		},
+		{
+			name:       "task without matrix",
+			expression: `[string(matrixWidth("clone"))]`,
+			expected:   []string{"1"},
+		},
+		{
+			name:       "unknown task",
+			expression: `[string(matrixWidth("deploy"))]`,
+			expected:   []string{"0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			programs, err := CompileCELPrograms([]string{tt.expression + `.map(v, annotation("result", v))`})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			mutations, err := programs[0].Evaluate(newTasksPipelineRun())
+			g.Expect(err).NotTo(HaveOccurred())
+			values := make([]string, 0, len(mutations))
+			for _, mutation := range mutations {
+				values = append(values, mutation.Value)
+			}
+			g.Expect(values).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestTaskFunctions_PipelineRef(t *testing.T) {
+	g := NewWithT(t)
+
+	programs, err := CompileCELPrograms([]string{
+		`[tasks().size(), finallyTasks().size(), taskRefs().size(), matrixWidth("build")].map(n, annotation("count", string(n)))`,
+		`annotation("has-task", string(hasTask("build")))`,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	pipelineRun := newRulesPipelineRun("tenant")
+	mutations, err := programs[0].Evaluate(pipelineRun)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mutations).To(HaveLen(4))
+	for _, mutation := range mutations {
+		g.Expect(mutation.Value).To(Equal("0"))
+	}
+
+	mutations, err = programs[1].Evaluate(pipelineRun)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mutations[0].Value).To(Equal("false"))
+}
@@ -0,0 +1,254 @@
+package cel
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	// Embed the IANA time zone database so that inTimeWindow() behaves the
+	// same in distroless images, which ship without /usr/share/zoneinfo.
+	_ "time/tzdata"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// minutesPerDay is the end of a time range that lasts until midnight, "24:00".
+const minutesPerDay = 24 * 60
+
+// weekdays maps the day names accepted in time windows to time.Weekday.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// timeWindows caches the windows parsed from literal arguments at compile
+// time, keyed by the window. Windows computed at evaluation time are not
+// cached, so that the cache stays bounded by the configuration.
+var timeWindows sync.Map
+
+// locations caches the time zones loaded by loadLocation, keyed by name. It
+// is bounded by the time zone database.
+var locations sync.Map
+
+// createTimeFunctions creates the inTimeWindow() helper. It is exposed to
+// users with the window and the time zone as arguments; a macro prepends the
+// hidden evaluation context, which carries the evaluation time.
+func createTimeFunctions() []cel.EnvOption {
+	return []cel.EnvOption{
+		createTimeWindowMacro(),
+		cel.Function("inTimeWindow",
+			cel.Overload("inTimeWindow_context_string_string_to_bool",
+				[]*cel.Type{cel.DynType, cel.StringType, cel.StringType},
+				cel.BoolType,
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					ctx, ok := contextFromVal(args[0])
+					if !ok {
+						return types.NewErr("inTimeWindow function requires the evaluation context")
+					}
+					window, windowOk := args[1].Value().(string)
+					timeZone, timeZoneOk := args[2].Value().(string)
+					if !windowOk || !timeZoneOk {
+						return types.NewErr("inTimeWindow function requires string arguments")
+					}
+
+					tw, err := cachedTimeWindow(window)
+					if err != nil {
+						return types.NewErr("inTimeWindow: %v", err)
+					}
+					location, err := loadLocation(timeZone)
+					if err != nil {
+						return types.NewErr("inTimeWindow: %v", err)
+					}
+					return types.Bool(tw.contains(ctx.now.In(location)))
+				}),
+			),
+		),
+	}
+}
+
+// createTimeWindowMacro creates the macro prepending the evaluation context
+// to inTimeWindow() calls. Literal arguments are parsed at compile time, so
+// that an invalid window or time zone fails the config reload rather than
+// the admission, and are not parsed again on evaluation.
+func createTimeWindowMacro() cel.EnvOption {
+	return cel.Macros(cel.GlobalMacro("inTimeWindow", 2,
+		func(eh cel.MacroExprFactory, _ ast.Expr, args []ast.Expr) (ast.Expr, *common.Error) {
+			if window, ok := literalString(args[0]); ok {
+				tw, err := parseTimeWindow(window)
+				if err != nil {
+					return nil, eh.NewError(args[0].ID(), err.Error())
+				}
+				timeWindows.Store(window, tw)
+			}
+			if timeZone, ok := literalString(args[1]); ok {
+				if _, err := loadLocation(timeZone); err != nil {
+					return nil, eh.NewError(args[1].ID(), err.Error())
+				}
+			}
+			return newContextCall(eh, "inTimeWindow", args), nil
+		},
+	))
+}
+
+// literalString returns the value of expr if it is a string literal.
+func literalString(expr ast.Expr) (string, bool) {
+	if expr.Kind() != ast.LiteralKind {
+		return "", false
+	}
+	value, ok := expr.AsLiteral().Value().(string)
+	return value, ok
+}
+
+// cachedTimeWindow returns the window parsed at compile time, or parses a
+// window computed at evaluation time.
+func cachedTimeWindow(window string) (*timeWindow, error) {
+	if tw, ok := timeWindows.Load(window); ok {
+		return tw.(*timeWindow), nil
+	}
+	return parseTimeWindow(window)
+}
+
+// loadLocation loads an IANA time zone. The local time zone of the process
+// is rejected so that evaluation does not depend on where it runs.
+func loadLocation(name string) (*time.Location, error) {
+	if location, ok := locations.Load(name); ok {
+		return location.(*time.Location), nil
+	}
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("time zone must be an IANA name such as \"Europe/Prague\" or \"UTC\", got %q", name)
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q: %w", name, err)
+	}
+	locations.Store(name, location)
+	return location, nil
+}
+
+// timeWindow is a recurring weekly time window, such as "Mon-Fri 08:00-18:00".
+type timeWindow struct {
+	// days holds the days the window starts on, indexed by time.Weekday
+	days [7]bool
+	// start and end are minutes since midnight; end is exclusive and is
+	// smaller than start for windows that span midnight
+	start, end int
+}
+
+// parseTimeWindow parses a window made of a day list, a time range or both:
+//
+//	"Mon-Fri 08:00-18:00"  working hours
+//	"Sat,Sun"              the whole weekend
+//	"22:00-06:00"          every night, spanning midnight
+//	"Fri 18:00-24:00"      Friday evening
+//
+// Days are three-letter English names, separated by commas, and ranges such
+// as "Fri-Mon" may wrap around the week. A window spanning midnight belongs to
+// the day it starts on.
+func parseTimeWindow(window string) (*timeWindow, error) {
+	tw := &timeWindow{start: 0, end: minutesPerDay}
+	fields := strings.Fields(window)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid time window %q: expected days, a time range or both", window)
+	}
+
+	// A single field is a time range if it contains a clock time
+	daysField, timeField := "", ""
+	switch {
+	case len(fields) == 2:
+		daysField, timeField = fields[0], fields[1]
+	case strings.Contains(fields[0], ":"):
+		timeField = fields[0]
+	default:
+		daysField = fields[0]
+	}
+
+	var err error
+	tw.days = [7]bool{true, true, true, true, true, true, true}
+	if daysField != "" {
+		if tw.days, err = parseDays(daysField); err != nil {
+			return nil, fmt.Errorf("invalid time window %q: %w", window, err)
+		}
+	}
+	if timeField != "" {
+		if tw.start, tw.end, err = parseTimeRange(timeField); err != nil {
+			return nil, fmt.Errorf("invalid time window %q: %w", window, err)
+		}
+	}
+	return tw, nil
+}
+
+// parseDays parses a comma-separated list of days and day ranges.
+func parseDays(field string) ([7]bool, error) {
+	var days [7]bool
+	for _, part := range strings.Split(field, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		from, ok := weekdays[strings.ToLower(first)]
+		if !ok {
+			return days, fmt.Errorf("unknown day %q", first)
+		}
+		to := from
+		if isRange {
+			if to, ok = weekdays[strings.ToLower(last)]; !ok {
+				return days, fmt.Errorf("unknown day %q", last)
+			}
+		}
+		for day := from; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == to {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseTimeRange parses a "HH:MM-HH:MM" range into minutes since midnight.
+func parseTimeRange(field string) (int, int, error) {
+	first, last, ok := strings.Cut(field, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("time range %q must be of the form HH:MM-HH:MM", field)
+	}
+	start, err := parseClock(first, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClock(last, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("time range %q is empty", field)
+	}
+	return start, end, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight. "24:00" is only
+// accepted as the end of a range.
+func parseClock(value string, end bool) (int, error) {
+	if end && value == "24:00" {
+		return minutesPerDay, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains reports whether t, already in the window's time zone, falls
+// inside the window.
+func (tw *timeWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if tw.start < tw.end {
+		return tw.days[day] && minute >= tw.start && minute < tw.end
+	}
+	// The window spans midnight: the early part belongs to the previous day
+	previous := (day + 6) % 7
+	return (tw.days[day] && minute >= tw.start) || (tw.days[previous] && minute < tw.end)
+}
@@ -0,0 +1,280 @@
+package cel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// evaluateAt evaluates a single expression with a fixed evaluation time.
+func evaluateAt(expression string, now time.Time) ([]*MutationRequest, error) {
+	programs, err := CompileCELPrograms([]string{expression})
+	if err != nil {
+		return nil, err
+	}
+	vars, err := newActivation(newRulesPipelineRun("tenant"), Inputs{Now: now})
+	if err != nil {
+		return nil, err
+	}
+	return programs[0].evaluate(context.Background(), vars)
+}
+
+func TestInTimeWindow(t *testing.T) {
+	// 2025-06-02 is a Monday
+	prague, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		window   string
+		timeZone string
+		now      time.Time
+		expected bool
+	}{
+		{
+			name:     "working hours",
+			window:   "Mon-Fri 08:00-18:00",
+			timeZone: "Europe/Prague",
+			now:      time.Date(2025, 6, 2, 9, 30, 0, 0, prague),
+			expected: true,
+		},
+		{
+			name:     "time zone is applied",
+			window:   "Mon-Fri 08:00-18:00",
+			timeZone: "Europe/Prague",
+			now:      time.Date(2025, 6, 2, 16, 30, 0, 0, time.UTC), // 18:30 in Prague
+			expected: false,
+		},
+		{
+			name:     "start is inclusive",
+			window:   "Mon-Fri 08:00-18:00",
+			timeZone: "UTC",
+			now:      time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "end is exclusive",
+			window:   "Mon-Fri 08:00-18:00",
+			timeZone: "UTC",
+			now:      time.Date(2025, 6, 2, 18, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+		{
+			name:     "outside the days",
+			window:   "Mon-Fri 08:00-18:00",
+			timeZone: "UTC",
+			now:      time.Date(2025, 6, 7, 9, 0, 0, 0, time.UTC), // Saturday
+			expected: false,
+		},
+		{
+			name:     "days only",
+			window:   "Sat,Sun",
+			timeZone: "UTC",
+			now:      time.Date(2025, 6, 8, 23, 59, 0, 0, time.UTC), // Sunday
+			expected: true,
+		},
+		{
+			name:     "day range wrapping around the week",
+			window:   "Fri-Mon",
+			timeZone: "UTC",
+			now:      time.Date(2025, 6, 8, 12, 0, 0, 0, time.UTC), // Sunday
+			expected: true,
+		},
+		{
+			name:     "time range only",
+			window:   "08:00-18:00",
+			timeZone: "UTC",
+			now:      time.Date(2025, 6, 8, 12, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "overnight window before midnight",
+			window:   "Fri 22:00-06:00",
+			timeZone: "UTC",
+			now:      time.Date(2025, 6, 6, 23, 0, 0, 0, time.UTC), // Friday
+			expected: true,
+		},
+		{
+			name:     "overnight window after midnight belongs to the previous day",
+			window:   "Fri 22:00-06:00",
+			timeZone: "UTC",
+			now:      time.Date(2025, 6, 7, 5, 59, 0, 0, time.UTC), // Saturday
+			expected: true,
+		},
+		{
+			name:     "overnight window after midnight of another day",
+			window:   "Fri 22:00-06:00",
+			timeZone: "UTC",
+			now:      time.Date(2025, 6, 6, 5, 0, 0, 0, time.UTC), // Friday
+			expected: false,
+		},
+		{
+			name:     "until midnight",
+			window:   "Mon 18:00-24:00",
+			timeZone: "UTC",
+			now:      time.Date(2025, 6, 2, 23, 59, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "lower case days",
+			window:   "mon-fri",
+			timeZone: "UTC",
+			now:      time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			expression := `inTimeWindow("` + tt.window + `", "` + tt.timeZone + `") ? [label("window", "in")] : [label("window", "out")]`
+			mutations, err := evaluateAt(expression, tt.now)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			expected := "out"
+			if tt.expected {
+				expected = "in"
+			}
+			g.Expect(mutations).To(HaveLen(1))
+			g.Expect(mutations[0].Value).To(Equal(expected))
+		})
+	}
+}
+
+func TestInTimeWindow_CompileErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		errMsg     string
+	}{
+		{
+			name:       "unknown day",
+			expression: `inTimeWindow("Mon-Fry", "UTC")`,
+			errMsg:     `invalid time window "Mon-Fry": unknown day "Fry"`,
+		},
+		{
+			name:       "invalid time",
+			expression: `inTimeWindow("Mon 8-18", "UTC")`,
+			errMsg:     `invalid time window "Mon 8-18": invalid time "8", expected HH:MM`,
+		},
+		{
+			name:       "invalid clock",
+			expression: `inTimeWindow("Mon 08:00-25:00", "UTC")`,
+			errMsg:     `invalid time "25:00", expected HH:MM`,
+		},
+		{
+			name:       "empty time range",
+			expression: `inTimeWindow("08:00-08:00", "UTC")`,
+			errMsg:     `time range "08:00-08:00" is empty`,
+		},
+		{
+			name:       "two time ranges",
+			expression: `inTimeWindow("08:00-10:00 12:00-14:00", "UTC")`,
+			errMsg:     `unknown day "08:00"`,
+		},
+		{
+			name:       "too many fields",
+			expression: `inTimeWindow("Mon 08:00-10:00 UTC", "UTC")`,
+			errMsg:     `expected days, a time range or both`,
+		},
+		{
+			name:       "empty window",
+			expression: `inTimeWindow("", "UTC")`,
+			errMsg:     `expected days, a time range or both`,
+		},
+		{
+			name:       "unknown time zone",
+			expression: `inTimeWindow("Mon-Fri", "Europe/Atlantis")`,
+			errMsg:     `unknown time zone "Europe/Atlantis"`,
+		},
+		{
+			name:       "local time zone",
+			expression: `inTimeWindow("Mon-Fri", "Local")`,
+			errMsg:     `time zone must be an IANA name`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			expression := tt.expression + ` ? [label("window", "in")] : []`
+			_, err := CompileCELPrograms([]string{expression})
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+		})
+	}
+}
+
+func TestInTimeWindow_EvaluationErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		errMsg     string
+	}{
+		{
+			name:       "computed window",
+			expression: `inTimeWindow(plrNamespace, "UTC")`,
+			errMsg:     `invalid time window "tenant": unknown day "tenant"`,
+		},
+		{
+			name:       "computed time zone",
+			expression: `inTimeWindow("Mon-Fri", plrNamespace + "/Prague")`,
+			errMsg:     `unknown time zone "tenant/Prague"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			expression := tt.expression + ` ? [label("window", "in")] : []`
+			_, err := CompileCELPrograms([]string{expression})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			_, err = evaluateAt(expression, time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC))
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+		})
+	}
+}
+
+func TestInTimeWindow_ComputedArguments(t *testing.T) {
+	g := NewWithT(t)
+
+	mutations, err := evaluateAt(
+		`inTimeWindow(plrNamespace == "tenant" ? "Mon-Fri" : "Sat,Sun", "Europe/" + "Prague") ? [label("window", "in")] : []`,
+		time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC), // Monday
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mutations).To(HaveLen(1))
+	g.Expect(mutations[0].Value).To(Equal("in"))
+}
+
+func TestNowVariable(t *testing.T) {
+	g := NewWithT(t)
+
+	mutations, err := evaluateAt(
+		`[annotation("hour", string(now.getHours("Europe/Prague"))), annotation("now", string(now))]`,
+		time.Date(2025, 6, 2, 7, 30, 0, 0, time.UTC),
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mutations).To(HaveLen(2))
+	g.Expect(mutations[0].Value).To(Equal("9"))
+	g.Expect(mutations[1].Value).To(Equal("2025-06-02T07:30:00Z"))
+}
+
+func TestNowVariable_DefaultsToCurrentTime(t *testing.T) {
+	g := NewWithT(t)
+
+	before := time.Now()
+	mutations, err := evaluateAt(`annotation("now", string(now))`, time.Time{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	now, err := time.Parse(time.RFC3339Nano, mutations[0].Value)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(now).To(BeTemporally(">=", before.Truncate(time.Second)))
+}
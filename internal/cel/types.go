@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"slices"
+
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
 )
 
 // MutationType represents the type of mutation to perform
@@ -14,6 +16,10 @@ const (
 	MutationTypeAnnotation MutationType = "annotation"
 	MutationTypeLabel      MutationType = "label"
 	MutationTypeResource   MutationType = "resource"
+	MutationTypeQueue      MutationType = "queue"
+	// Removal mutations delete a key and carry no value
+	MutationTypeRemoveLabel      MutationType = "removeLabel"
+	MutationTypeRemoveAnnotation MutationType = "removeAnnotation"
 )
 
 // IsValid checks if the mutation type is valid
@@ -28,7 +34,15 @@ func (mt MutationType) String() string {
 
 // ValidTypes returns all valid mutation types
 func ValidTypes() []MutationType {
-	return []MutationType{MutationTypeAnnotation, MutationTypeLabel, MutationTypeResource}
+	return []MutationType{
+		MutationTypeAnnotation, MutationTypeLabel, MutationTypeResource, MutationTypeQueue,
+		MutationTypeRemoveLabel, MutationTypeRemoveAnnotation,
+	}
+}
+
+// IsRemoval reports whether the mutation type deletes a key instead of setting a value
+func (mt MutationType) IsRemoval() bool {
+	return mt == MutationTypeRemoveLabel || mt == MutationTypeRemoveAnnotation
 }
 
 // UnmarshalJSON implements json.Unmarshaler interface with validation
@@ -57,6 +71,9 @@ type MutationRequest struct {
 	Type  MutationType `json:"type"`
 	Key   string       `json:"key"`
 	Value string       `json:"value"`
+	// Aggregation optionally overrides the configured aggregation of a
+	// resource mutation, e.g. "max" for mutations created by resourceMax()
+	Aggregation config.ResourceAggregation `json:"aggregation,omitempty"`
 }
 
 // Validate ensures the MutationRequest is valid
@@ -67,6 +84,20 @@ func (mr *MutationRequest) Validate() error {
 	if mr.Key == "" {
 		return fmt.Errorf("mutation key cannot be empty")
 	}
+	if mr.Aggregation != "" {
+		if mr.Type != MutationTypeResource {
+			return fmt.Errorf("%s mutation cannot have an aggregation", mr.Type)
+		}
+		if !slices.Contains(validResourceAggregations(), mr.Aggregation) {
+			return fmt.Errorf("invalid aggregation: %q, must be one of: %v", mr.Aggregation, validResourceAggregations())
+		}
+	}
+	if mr.Type.IsRemoval() {
+		if mr.Value != "" {
+			return fmt.Errorf("%s mutation cannot have a value", mr.Type)
+		}
+		return nil
+	}
 	if mr.Value == "" {
 		return fmt.Errorf("mutation value cannot be empty")
 	}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
 	. "github.com/onsi/gomega"
 )
 
@@ -16,6 +17,9 @@ func TestMutationType_IsValid(t *testing.T) {
 		{"valid annotation", MutationTypeAnnotation, true},
 		{"valid label", MutationTypeLabel, true},
 		{"valid resource", MutationTypeResource, true},
+		{"valid queue", MutationTypeQueue, true},
+		{"valid removeLabel", MutationTypeRemoveLabel, true},
+		{"valid removeAnnotation", MutationTypeRemoveAnnotation, true},
 		{"invalid type", MutationType("invalid"), false},
 		{"empty type", MutationType(""), false},
 	}
@@ -54,6 +58,18 @@ func TestMutationType_JSON(t *testing.T) {
 			expectErr: false,
 			expected:  MutationTypeResource,
 		},
+		{
+			name:      "valid queue",
+			input:     `"queue"`,
+			expectErr: false,
+			expected:  MutationTypeQueue,
+		},
+		{
+			name:      "valid removeLabel",
+			input:     `"removeLabel"`,
+			expectErr: false,
+			expected:  MutationTypeRemoveLabel,
+		},
 		{
 			name:      "invalid type",
 			input:     `"invalid"`,
@@ -112,6 +128,56 @@ func TestMutationRequest_Validate(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			name: "valid removeLabel without value",
+			request: MutationRequest{
+				Type: MutationTypeRemoveLabel,
+				Key:  "kueue.x-k8s.io/priority-class",
+			},
+			expectErr: false,
+		},
+		{
+			name: "removeAnnotation with value",
+			request: MutationRequest{
+				Type:  MutationTypeRemoveAnnotation,
+				Key:   "test-key",
+				Value: "test-value",
+			},
+			expectErr: true,
+			errMsg:    "removeAnnotation mutation cannot have a value",
+		},
+		{
+			name: "resource with aggregation",
+			request: MutationRequest{
+				Type:        MutationTypeResource,
+				Key:         "kueue.konflux-ci.dev/requests-cpu",
+				Value:       "2",
+				Aggregation: config.ResourceAggregationMax,
+			},
+			expectErr: false,
+		},
+		{
+			name: "resource with invalid aggregation",
+			request: MutationRequest{
+				Type:        MutationTypeResource,
+				Key:         "kueue.konflux-ci.dev/requests-cpu",
+				Value:       "2",
+				Aggregation: "avg",
+			},
+			expectErr: true,
+			errMsg:    `invalid aggregation: "avg"`,
+		},
+		{
+			name: "label with aggregation",
+			request: MutationRequest{
+				Type:        MutationTypeLabel,
+				Key:         "test-key",
+				Value:       "test-value",
+				Aggregation: config.ResourceAggregationMax,
+			},
+			expectErr: true,
+			errMsg:    "label mutation cannot have an aggregation",
+		},
 		{
 			name: "invalid type",
 			request: MutationRequest{
@@ -0,0 +1,168 @@
+package cel
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+)
+
+const (
+	// variablesVariable is the name of the CEL variable holding the
+	// configured variables.
+	variablesVariable = "variables"
+
+	// variablesTypeName is the CEL object type of the variables variable.
+	// Its fields are the configured variables, typed after their expressions.
+	variablesTypeName = "tektonkueue.Variables"
+)
+
+// variableNamePattern matches CEL identifiers.
+var variableNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// variablesType is the CEL type reported by variableValues.
+var variablesType = types.NewObjectType(variablesTypeName)
+
+// variableSet holds the compiled variables of a configuration. It is shared
+// by all programs compiled from the configuration.
+type variableSet struct {
+	schema    *schema
+	variables map[string]*compiledVariable
+}
+
+// compiledVariable is a compiled variable expression.
+type compiledVariable struct {
+	expression string
+	program    cel.Program
+}
+
+// compileVariables compiles the variables of a configuration in declaration
+// order and returns the environment extended with the variables variable.
+// Each variable is type checked with the variables declared before it, so a
+// variable cannot depend on itself or on a later one.
+func compileVariables(env *cel.Env, variables []config.Variable, costLimit uint64) (*cel.Env, *variableSet, error) {
+	object := &schemaObject{fields: map[string]*types.Type{}}
+	set := &variableSet{
+		schema:    &schema{objects: map[string]*schemaObject{variablesTypeName: object}},
+		variables: make(map[string]*compiledVariable, len(variables)),
+	}
+
+	env, err := env.Extend(
+		withSchema(set.schema),
+		cel.Variable(variablesVariable, variablesType),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to declare variables: %w", err)
+	}
+
+	for i, variable := range variables {
+		if !variableNamePattern.MatchString(variable.Name) {
+			return nil, nil, fmt.Errorf("cel.variables[%d].name: invalid name %q: must be a CEL identifier", i, variable.Name)
+		}
+		if _, found := set.variables[variable.Name]; found {
+			return nil, nil, fmt.Errorf("cel.variables[%d].name: duplicate variable name %q", i, variable.Name)
+		}
+		if variable.Expression == "" {
+			return nil, nil, fmt.Errorf("cel.variables[%d].expression: expression of variable %q cannot be empty", i, variable.Name)
+		}
+
+		ast, issues := env.Compile(variable.Expression)
+		if issues != nil && issues.Err() != nil {
+			return nil, nil, fmt.Errorf("cel.variables[%d].expression: type checking failed for variable %q: %w", i, variable.Name, issues.Err())
+		}
+		if err := checkCost(env, ast, costLimit); err != nil {
+			return nil, nil, fmt.Errorf("cel.variables[%d].expression: cost check failed for variable %q: %w", i, variable.Name, err)
+		}
+		program, err := env.Program(ast, programOptions(costLimit)...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cel.variables[%d].expression: program creation failed for variable %q: %w", i, variable.Name, err)
+		}
+
+		// Declaring the field makes the variable visible to the following
+		// variables and to the rules
+		object.names = append(object.names, variable.Name)
+		object.fields[variable.Name] = ast.OutputType()
+		set.variables[variable.Name] = &compiledVariable{expression: variable.Expression, program: program}
+	}
+	return env, set, nil
+}
+
+// bind exposes the variables in the activation of a PipelineRun. The values
+// are shared by all programs evaluated with the activation, so that each
+// variable is evaluated at most once.
+func (s *variableSet) bind(ctx context.Context, vars map[string]interface{}) {
+	if values, ok := vars[variablesVariable].(*variableValues); ok && values.set == s {
+		return
+	}
+	vars[variablesVariable] = &variableValues{
+		ctx:     ctx,
+		set:     s,
+		vars:    vars,
+		results: make(map[string]ref.Val, len(s.variables)),
+	}
+}
+
+// variableValues evaluates variables lazily, the first time an expression
+// selects them, and caches the results, including errors, for the rest of
+// the admission.
+type variableValues struct {
+	ctx     context.Context
+	set     *variableSet
+	vars    map[string]interface{}
+	results map[string]ref.Val
+}
+
+// Get implements traits.Indexer.
+func (v *variableValues) Get(index ref.Val) ref.Val {
+	name, ok := index.Value().(string)
+	if !ok {
+		return types.NewErr("variables must be selected by name, got %s", index.Type())
+	}
+	if result, found := v.results[name]; found {
+		return result
+	}
+	variable, found := v.set.variables[name]
+	if !found {
+		return types.NewErr("no such variable: %s", name)
+	}
+
+	result, _, err := variable.program.ContextEval(v.ctx, v.vars)
+	if err != nil {
+		result = types.WrapErr(fmt.Errorf("failed to evaluate variable %q: %w", name, err))
+	}
+	v.results[name] = result
+	return result
+}
+
+// ConvertToNative implements ref.Val.
+func (v *variableValues) ConvertToNative(typeDesc reflect.Type) (any, error) {
+	return nil, fmt.Errorf("type conversion error from '%s' to '%v'", variablesTypeName, typeDesc)
+}
+
+// ConvertToType implements ref.Val.
+func (v *variableValues) ConvertToType(typeVal ref.Type) ref.Val {
+	if typeVal == types.TypeType {
+		return variablesType
+	}
+	return types.NewErr("type conversion error from '%s' to '%s'", variablesTypeName, typeVal)
+}
+
+// Equal implements ref.Val.
+func (v *variableValues) Equal(other ref.Val) ref.Val {
+	return types.Bool(v == other)
+}
+
+// Type implements ref.Val.
+func (v *variableValues) Type() ref.Type {
+	return variablesType
+}
+
+// Value implements ref.Val.
+func (v *variableValues) Value() any {
+	return v
+}
@@ -0,0 +1,192 @@
+package cel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	. "github.com/onsi/gomega"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+)
+
+func newVariablesPipelineRun() *tekv1.PipelineRun {
+	pipelineRun := newRulesPipelineRun("tenant")
+	pipelineRun.Spec.Params = []tekv1.Param{
+		{Name: "revision", Value: *tekv1.NewStructuredValues("main")},
+		{Name: "build-platforms", Value: *tekv1.NewStructuredValues("linux/amd64", "linux/arm64")},
+	}
+	return pipelineRun
+}
+
+func TestCELMutator_Mutate_Variables(t *testing.T) {
+	variables := []config.Variable{
+		{Name: "platforms", Expression: `paramArray("build-platforms")`},
+		{Name: "platformCount", Expression: `variables.platforms.size()`},
+		{Name: "isMultiArch", Expression: `variables.platformCount > 1`},
+		{Name: "missing", Expression: `param("missing")`},
+	}
+
+	tests := []struct {
+		name           string
+		cfg            config.CEL
+		expectedLabels map[string]string
+		errMsg         string
+	}{
+		{
+			name: "rules share variables",
+			cfg: config.CEL{
+				Expressions: []string{`label("platforms", string(variables.platformCount))`},
+				Rules: []config.Rule{
+					{Name: "multi-arch", When: `variables.isMultiArch`, Mutations: `priority("konflux-low")`},
+				},
+			},
+			expectedLabels: map[string]string{
+				"platforms":                     "2",
+				"kueue.x-k8s.io/priority-class": "konflux-low",
+			},
+		},
+		{
+			name: "unused variables are not evaluated",
+			cfg: config.CEL{
+				Expressions: []string{`label("multi-arch", string(variables.isMultiArch))`},
+			},
+			expectedLabels: map[string]string{"multi-arch": "true"},
+		},
+		{
+			name: "failing variable fails the rule",
+			cfg: config.CEL{
+				Rules: []config.Rule{
+					{Name: "revision", Mutations: `label("revision", variables.missing)`},
+				},
+			},
+			errMsg: `rule "revision": failed to evaluate CEL expression "label(\"revision\", variables.missing)": failed to evaluate variable "missing"`,
+		},
+		{
+			name: "failing variable is handled by onError",
+			cfg: config.CEL{
+				Rules: []config.Rule{
+					{Name: "revision", Mutations: `label("revision", variables.missing)`, OnError: config.OnErrorSkip},
+					{Name: "platforms", Mutations: `label("platforms", string(variables.platformCount))`},
+				},
+			},
+			expectedLabels: map[string]string{"platforms": "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			tt.cfg.Variables = variables
+			programs, err := CompileConfig(tt.cfg)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			pipelineRun := newVariablesPipelineRun()
+			err = NewCELMutator(programs).Mutate(context.Background(), pipelineRun, Inputs{})
+			if tt.errMsg != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(pipelineRun.Labels).To(Equal(tt.expectedLabels))
+		})
+	}
+}
+
+func TestCompileConfig_VariableErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables []config.Variable
+		mutations string
+		errMsg    string
+	}{
+		{
+			name:      "invalid name",
+			variables: []config.Variable{{Name: "is-fork", Expression: `true`}},
+			errMsg:    `cel.variables[0].name: invalid name "is-fork": must be a CEL identifier`,
+		},
+		{
+			name:      "duplicate name",
+			variables: []config.Variable{{Name: "isFork", Expression: `true`}, {Name: "isFork", Expression: `false`}},
+			errMsg:    `cel.variables[1].name: duplicate variable name "isFork"`,
+		},
+		{
+			name:      "empty expression",
+			variables: []config.Variable{{Name: "isFork", Expression: ``}},
+			errMsg:    `expression of variable "isFork" cannot be empty`,
+		},
+		{
+			name: "reference to a later variable",
+			variables: []config.Variable{
+				{Name: "isMultiArch", Expression: `variables.platformCount > 1`},
+				{Name: "platformCount", Expression: `paramArray("build-platforms").size()`},
+			},
+			errMsg: `cel.variables[0].expression: type checking failed for variable "isMultiArch": ERROR: <input>:1:10: undefined field 'platformCount'`,
+		},
+		{
+			name:      "reference to itself",
+			variables: []config.Variable{{Name: "loop", Expression: `variables.loop`}},
+			errMsg:    `undefined field 'loop'`,
+		},
+		{
+			name:      "unknown variable in a rule",
+			variables: []config.Variable{{Name: "isFork", Expression: `false`}},
+			mutations: `variables.isFrok ? [label("fork", "true")] : []`,
+			errMsg:    `undefined field 'isFrok'`,
+		},
+		{
+			name:      "variable used with the wrong type",
+			variables: []config.Variable{{Name: "platformCount", Expression: `paramArray("build-platforms").size()`}},
+			mutations: `label("platforms", variables.platformCount)`,
+			errMsg:    `found no matching overload for 'label' applied to '(string, int)'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mutations := tt.mutations
+			if mutations == "" {
+				mutations = `label("env", "test")`
+			}
+			_, err := CompileConfig(config.CEL{
+				Variables: tt.variables,
+				Rules:     []config.Rule{{Name: "rule", Mutations: mutations}},
+			})
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errMsg)))
+		})
+	}
+}
+
+func TestCompileConfig_NoVariables(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := CompileCELPrograms([]string{`variables.isFork ? [label("fork", "true")] : []`})
+	g.Expect(err).To(MatchError(ContainSubstring(`undeclared reference to 'variables'`)))
+}
+
+func TestVariableValues_Cache(t *testing.T) {
+	g := NewWithT(t)
+
+	programs, err := CompileConfig(config.CEL{
+		Variables:   []config.Variable{{Name: "revision", Expression: `param("revision")`}},
+		Expressions: []string{`label("revision", variables.revision)`},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	vars, err := newActivation(newVariablesPipelineRun(), Inputs{})
+	g.Expect(err).NotTo(HaveOccurred())
+	programs[0].variables.bind(context.Background(), vars)
+	values := vars[variablesVariable].(*variableValues)
+
+	g.Expect(values.Get(types.String("revision"))).To(Equal(types.String("main")))
+	g.Expect(values.results).To(HaveKeyWithValue("revision", types.String("main")))
+
+	// Later selections, from any program, are served from the cache
+	values.results["revision"] = types.String("cached")
+	mutations, err := programs[0].evaluate(context.Background(), vars)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mutations[0].Value).To(Equal("cached"))
+}
@@ -8,16 +8,22 @@ import (
 	"github.com/konflux-ci/tekton-kueue/pkg/common"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-// ConfigMapReconciler watches the tekton-kueue-config ConfigMap and propagates
-// configuration changes to the webhook's ConfigStore. This allows queue names,
-// multiKueue settings, and CEL mutation expressions to be updated at runtime
-// without restarting the webhook pod.
+// ConfigMapReconciler watches the tekton-kueue-config ConfigMap, and the
+// config fragment ConfigMaps labelled with common.ConfigFragmentLabel in the
+// same namespace, and propagates configuration changes to the webhook's
+// ConfigStore. This allows queue names, multiKueue settings, and CEL mutation
+// expressions to be updated at runtime without restarting the webhook pod.
 type ConfigMapReconciler struct {
 	Client client.Client
 	Store  *v1.ConfigStore
@@ -28,15 +34,39 @@ func (r *ConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if err != nil {
 		return err
 	}
+	isConfig := func(o client.Object) bool {
+		return o.GetNamespace() == namespace &&
+			(o.GetName() == common.ConfigMapName || isConfigFragment(o))
+	}
+	// Every change, including to a fragment, reloads the whole configuration,
+	// so all events are mapped to the tekton-kueue-config ConfigMap
+	configKey := types.NamespacedName{Namespace: namespace, Name: common.ConfigMapName}
 	return ctrl.NewControllerManagedBy(mgr).
 		Named("webhook-config").
-		For(&corev1.ConfigMap{}).
-		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
-			return o.GetName() == common.ConfigMapName && o.GetNamespace() == namespace
-		})).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(func(context.Context, client.Object) []reconcile.Request {
+				return []reconcile.Request{{NamespacedName: configKey}}
+			}),
+			// The old object of an update matters when the fragment label
+			// is removed
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc:  func(e event.CreateEvent) bool { return isConfig(e.Object) },
+				UpdateFunc:  func(e event.UpdateEvent) bool { return isConfig(e.ObjectOld) || isConfig(e.ObjectNew) },
+				DeleteFunc:  func(e event.DeleteEvent) bool { return isConfig(e.Object) },
+				GenericFunc: func(e event.GenericEvent) bool { return isConfig(e.Object) },
+			}),
+		).
 		Complete(r)
 }
 
+// isConfigFragment reports whether a ConfigMap holds a config fragment.
+func isConfigFragment(o client.Object) bool {
+	return o.GetLabels()[common.ConfigFragmentLabel] == "true" && o.GetName() != common.ConfigMapName
+}
+
+// Reconcile loads the tekton-kueue-config ConfigMap named by req and the
+// config fragments of its namespace, and updates the ConfigStore with them.
 func (r *ConfigMapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	var cm corev1.ConfigMap
@@ -53,9 +83,41 @@ func (r *ConfigMapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		logger.Info("Key is not present in configmap", "ConfigKey", common.ConfigKey, "ConfigMap", req.NamespacedName)
 		return ctrl.Result{}, nil
 	}
-	if err := r.Store.Update([]byte(raw)); err != nil {
+	fragments, err := r.listFragments(ctx, req.Namespace)
+	if err != nil {
+		logger.Error(err, "unable to list config fragments", "Namespace", req.Namespace)
+		return ctrl.Result{}, err
+	}
+	if err := r.Store.Update([]byte(raw), fragments...); err != nil {
 		logger.Error(err, "unable to update config")
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, err
 	}
 	return ctrl.Result{}, nil
 }
+
+// listFragments reads the config fragments of the namespace. Each fragment
+// is named after its ConfigMap; ConfigMaps without the config key are
+// skipped.
+func (r *ConfigMapReconciler) listFragments(ctx context.Context, namespace string) ([]v1.ConfigFragment, error) {
+	logger := log.FromContext(ctx)
+	var cms corev1.ConfigMapList
+	if err := r.Client.List(ctx, &cms,
+		client.InNamespace(namespace), client.MatchingLabels{common.ConfigFragmentLabel: "true"},
+	); err != nil {
+		return nil, err
+	}
+	fragments := make([]v1.ConfigFragment, 0, len(cms.Items))
+	for i := range cms.Items {
+		cm := &cms.Items[i]
+		if !isConfigFragment(cm) {
+			continue
+		}
+		raw, ok := cm.Data[common.ConfigKey]
+		if !ok {
+			logger.Info("Key is not present in config fragment", "ConfigKey", common.ConfigKey, "ConfigMap", cm.Name)
+			continue
+		}
+		fragments = append(fragments, v1.ConfigFragment{Name: cm.Name, Data: []byte(raw)})
+	}
+	return fragments, nil
+}
@@ -164,5 +164,82 @@ var _ = Describe("ConfigMapReconciler", func() {
 			Expect(cfg.MultiKueueOverride).To(BeFalse())
 			Expect(mutators).To(BeEmpty())
 		})
+
+		Context("with config fragments", func() {
+			const fragment = `
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: ConfigFragment
+rules:
+  - name: %s
+    mutations: 'label("team", "%s")'
+`
+			newConfigMap := func(name, namespace string, fragmentLabel bool, data map[string]string) *corev1.ConfigMap {
+				cm := &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+					Data:       data,
+				}
+				if fragmentLabel {
+					cm.Labels = map[string]string{common.ConfigFragmentLabel: "true"}
+				}
+				return cm
+			}
+			mainConfigMap := func() *corev1.ConfigMap {
+				return newConfigMap(common.ConfigMapName, "tekton-kueue", false, map[string]string{
+					common.ConfigKey: "queueName: test-queue",
+				})
+			}
+
+			It("should merge the labelled fragments of the namespace", func(ctx context.Context) {
+				fakeClient := fake.NewClientBuilder().WithScheme(s).WithObjects(
+					mainConfigMap(),
+					newConfigMap("team-b", "tekton-kueue", true, map[string]string{
+						common.ConfigKey: fmt.Sprintf(fragment, "team-b", "team-b"),
+					}),
+					newConfigMap("team-a", "tekton-kueue", true, map[string]string{
+						common.ConfigKey: fmt.Sprintf(fragment, "team-a", "team-a"),
+					}),
+					// Ignored: without the config key
+					newConfigMap("team-c", "tekton-kueue", true, map[string]string{"other-key": "some-value"}),
+					// Ignored: without the label
+					newConfigMap("team-d", "tekton-kueue", false, map[string]string{
+						common.ConfigKey: fmt.Sprintf(fragment, "team-d", "team-d"),
+					}),
+					// Ignored: in another namespace
+					newConfigMap("team-e", "other", true, map[string]string{
+						common.ConfigKey: fmt.Sprintf(fragment, "team-e", "team-e"),
+					}),
+				).Build()
+				reconciler = &ConfigMapReconciler{Client: fakeClient, Store: store}
+
+				Expect(reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: nsName})).To(Equal(ctrl.Result{}))
+
+				cfg, mutators := store.GetConfigAndMutators()
+				Expect(cfg.QueueName).To(Equal("test-queue"))
+				Expect(cfg.CEL.Rules).To(HaveLen(2))
+				Expect(cfg.CEL.Rules[0].Name).To(Equal("team-a"))
+				Expect(cfg.CEL.Rules[1].Name).To(Equal("team-b"))
+				Expect(mutators).To(HaveLen(1))
+			})
+
+			It("should requeue when two fragments define the same rule", func(ctx context.Context) {
+				fakeClient := fake.NewClientBuilder().WithScheme(s).WithObjects(
+					mainConfigMap(),
+					newConfigMap("team-a", "tekton-kueue", true, map[string]string{
+						common.ConfigKey: fmt.Sprintf(fragment, "shared", "team-a"),
+					}),
+					newConfigMap("team-b", "tekton-kueue", true, map[string]string{
+						common.ConfigKey: fmt.Sprintf(fragment, "shared", "team-b"),
+					}),
+				).Build()
+				reconciler = &ConfigMapReconciler{Client: fakeClient, Store: store}
+
+				result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: nsName})
+				Expect(err).To(MatchError(ContainSubstring(`rule "shared" is already defined by config fragment "team-a"`)))
+				Expect(result.RequeueAfter).To(Equal(10 * time.Second))
+
+				cfg, _ := store.GetConfigAndMutators()
+				Expect(cfg).To(BeNil())
+			})
+		})
 	})
 })
@@ -0,0 +1,128 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// ConfigFragment is the raw YAML of a config fragment, named after where it
+// was read from, e.g. its ConfigMap or file. Fragments with the same
+// priority are merged in order of name.
+type ConfigFragment struct {
+	Name string
+	Data []byte
+}
+
+// parsedFragment is a decoded ConfigFragment.
+type parsedFragment struct {
+	name     string
+	raw      []byte
+	fragment config.ConfigFragment
+}
+
+// parseFragments decodes the fragments strictly and sorts them in merge
+// order: by priority, then by name.
+func parseFragments(fragments []ConfigFragment) ([]parsedFragment, error) {
+	parsed := make([]parsedFragment, 0, len(fragments))
+	for _, fragment := range fragments {
+		decoded, err := parseFragment(fragment.Data)
+		if err != nil {
+			return nil, fmt.Errorf("config fragment %q: %w", fragment.Name, err)
+		}
+		parsed = append(parsed, parsedFragment{name: fragment.Name, raw: fragment.Data, fragment: decoded})
+	}
+	slices.SortStableFunc(parsed, func(a, b parsedFragment) int {
+		return cmp.Or(cmp.Compare(a.fragment.Priority, b.fragment.Priority), strings.Compare(a.name, b.name))
+	})
+	return parsed, nil
+}
+
+// parseFragment decodes a fragment, which must have the current apiVersion
+// and the fragment kind. Unlike the config, fragments have no unversioned
+// format, so they are always decoded strictly.
+func parseFragment(raw []byte) (config.ConfigFragment, error) {
+	fragment := config.ConfigFragment{}
+	var typeMeta metav1.TypeMeta
+	if err := yaml.Unmarshal(raw, &typeMeta); err != nil {
+		return fragment, err
+	}
+	if typeMeta.APIVersion != config.APIVersion || typeMeta.Kind != config.FragmentKind {
+		return fragment, fmt.Errorf("unsupported apiVersion %q and kind %q, expected apiVersion %q and kind %q",
+			typeMeta.APIVersion, typeMeta.Kind, config.APIVersion, config.FragmentKind)
+	}
+	return fragment, parseStrict(raw, &fragment)
+}
+
+// mergeFragments appends the rules and queue routes of the fragments, in
+// order, to those of cfg, and adds their tables. A rule or table name that
+// is already defined fails the merge, naming both definitions.
+func mergeFragments(cfg *config.Config, fragments []parsedFragment) error {
+	ruleSources := make(map[string]string, len(cfg.CEL.Rules))
+	for _, rule := range cfg.CEL.Rules {
+		ruleSources[rule.Name] = "the main config"
+	}
+	tableSources := make(map[string]string, len(cfg.Tables))
+	for name := range cfg.Tables {
+		tableSources[name] = "the main config"
+	}
+
+	for _, fragment := range fragments {
+		source := fmt.Sprintf("config fragment %q", fragment.name)
+		for _, rule := range fragment.fragment.Rules {
+			if other, exists := ruleSources[rule.Name]; exists {
+				return fmt.Errorf("%s: rule %q is already defined by %s", source, rule.Name, other)
+			}
+			ruleSources[rule.Name] = source
+			cfg.CEL.Rules = append(cfg.CEL.Rules, rule)
+		}
+		for _, name := range slices.Sorted(maps.Keys(fragment.fragment.Tables)) {
+			if other, exists := tableSources[name]; exists {
+				return fmt.Errorf("%s: table %q is already defined by %s", source, name, other)
+			}
+			tableSources[name] = source
+			if cfg.Tables == nil {
+				cfg.Tables = make(map[string]config.Table)
+			}
+			cfg.Tables[name] = fragment.fragment.Tables[name]
+		}
+		// Validate the routes of each fragment, so that an invalid route
+		// is reported with its index in the fragment
+		if _, err := newQueueRouter(fragment.fragment.QueueRouting); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		cfg.QueueRouting = append(cfg.QueueRouting, fragment.fragment.QueueRouting...)
+	}
+	return nil
+}
+
+// fragmentNames lists the names of the fragments, for logging.
+func fragmentNames(fragments []ConfigFragment) []string {
+	names := make([]string, 0, len(fragments))
+	for _, fragment := range fragments {
+		names = append(names, fragment.Name)
+	}
+	return names
+}
@@ -2,23 +2,33 @@
 //
 // When a PipelineRun is created, the webhook intercepts it and:
 //  1. Sets it to Pending so Kueue can control when it starts
-//  2. Assigns it to a Kueue LocalQueue via a label
-//  3. Optionally sets the managedBy field for multiKueue
-//  4. Applies CEL-based mutations (labels, annotations, resource requests)
+//  2. Optionally sets the managedBy field for multiKueue
+//  3. Applies CEL-based mutations (labels, annotations, resource requests, queues)
+//  4. Assigns it to a Kueue LocalQueue via a label, unless a CEL queue()
+//     mutation or the user already did, using the first matching
+//     queueRouting entry or else the configured queueName
 //
-// Configuration is loaded from a ConfigMap and can be updated at runtime
-// via the ConfigMapReconciler in the controller package.
+// Configuration is loaded from a ConfigMap, merged with any config fragment
+// ConfigMaps, and can be updated at runtime via the ConfigMapReconciler in
+// the controller package.
 package v1
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"sync"
 
 	"github.com/konflux-ci/tekton-kueue/internal/cel"
 	"github.com/konflux-ci/tekton-kueue/pkg/config"
 	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	ctrl "sigs.k8s.io/controller-runtime"
+	kjson "sigs.k8s.io/json"
+	sigsyaml "sigs.k8s.io/yaml"
 )
 
 var (
@@ -32,28 +42,38 @@ type ConfigStore struct {
 	mu       sync.RWMutex
 	config   *config.Config
 	mutators []PipelineRunMutator
+	// router is nil if the config has no queueRouting
+	router *queueRouter
 }
 
 // PipelineRunMutator applies a mutation to a PipelineRun during webhook admission.
 // The primary implementation is cel.CELMutator, which evaluates user-defined
 // CEL expressions to dynamically set labels, annotations, or resource requests.
 type PipelineRunMutator interface {
-	Mutate(*tekv1.PipelineRun) error
+	Mutate(context.Context, *tekv1.PipelineRun, cel.Inputs) error
 }
 
 func (s *ConfigStore) GetConfigAndMutators() (*config.Config, []PipelineRunMutator) {
+	config, _, mutators := s.get()
+	return config, mutators
+}
+
+// get returns the config with its queue router and mutators, all from the
+// same update.
+func (s *ConfigStore) get() (*config.Config, *queueRouter, []PipelineRunMutator) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.config, s.mutators
+	return s.config, s.router, s.mutators
 }
 
-// Update parses and validates the raw YAML configuration, compiles any CEL
-// expressions, and atomically swaps the config and mutators. If any step fails,
-// the previous configuration is preserved (last-known-good behavior).
-func (s *ConfigStore) Update(rawConfig []byte) error {
+// Update parses and validates the raw YAML configuration, merges the config
+// fragments into it, compiles any CEL expressions and rules, and atomically
+// swaps the config and mutators. If any step fails, the previous
+// configuration is preserved (last-known-good behavior).
+func (s *ConfigStore) Update(rawConfig []byte, fragments ...ConfigFragment) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	logger.Info("Updating config", "config", string(rawConfig))
+	logger.Info("Updating config", "config", string(rawConfig), "fragments", fragmentNames(fragments))
 	cfg, err := parseConfig(rawConfig)
 	if err != nil {
 		RecordReloadFailure()
@@ -63,37 +83,152 @@ func (s *ConfigStore) Update(rawConfig []byte) error {
 		RecordReloadFailure()
 		return err
 	}
+	parsedFragments, err := parseFragments(fragments)
+	if err != nil {
+		RecordReloadFailure()
+		logger.Error(err, "failed to parse config fragments")
+		return err
+	}
+	if err := mergeFragments(&cfg, parsedFragments); err != nil {
+		RecordReloadFailure()
+		logger.Error(err, "failed to merge config fragments")
+		return err
+	}
+	if err := cel.ValidateTables(cfg.Tables); err != nil {
+		RecordReloadFailure()
+		logger.Error(err, "invalid tables")
+		return err
+	}
+	if err := cel.ValidateProfiles(cfg.Profiles); err != nil {
+		RecordReloadFailure()
+		logger.Error(err, "invalid profiles")
+		return err
+	}
+	generation := configGeneration(rawConfig, parsedFragments)
 	mutators := []PipelineRunMutator{}
-	if len(cfg.CEL.Expressions) != 0 {
-		programs, err := cel.CompileCELPrograms(cfg.CEL.Expressions)
+	if len(cfg.CEL.Expressions) != 0 || len(cfg.CEL.Rules) != 0 {
+		programs, err := cel.CompileConfig(cfg.CEL, cel.WithTables(cfg.Tables), cel.WithProfiles(cfg.Profiles))
 		if err != nil {
 			RecordReloadFailure()
 			logger.Error(err, "failed to compile CEL programs")
 			return err
 		}
-		mutators = append(mutators, cel.NewCELMutator(programs))
+		var opts []cel.MutatorOption
+		evaluationTimeout := cel.DefaultEvaluationTimeout
+		if cfg.CEL.EvaluationTimeout != nil {
+			evaluationTimeout = cfg.CEL.EvaluationTimeout.Duration
+		}
+		opts = append(opts, cel.WithEvaluationTimeout(evaluationTimeout))
+		opts = append(opts, cel.WithConflictPolicy(cfg.CEL.ConflictPolicy, cfg.CEL.PriorityClasses))
+		existingResources := cfg.CEL.ExistingResources
+		if existingResources == "" {
+			existingResources = cel.DefaultExistingResources
+		}
+		opts = append(opts, cel.WithResourceAggregation(cfg.CEL.ResourceAggregation, existingResources))
+		if cfg.CEL.Provenance {
+			opts = append(opts, cel.WithProvenance(generation))
+		}
+		mutators = append(mutators, cel.NewCELMutator(programs, opts...))
+	}
+	var router *queueRouter
+	if len(cfg.QueueRouting) != 0 {
+		router, err = newQueueRouter(cfg.QueueRouting)
+		if err != nil {
+			RecordReloadFailure()
+			logger.Error(err, "invalid queue routing")
+			return err
+		}
 	}
 	s.mutators = mutators
+	s.router = router
 	s.config = &cfg
 	RecordReloadSuccess()
-	logger.Info("Updated config", "config", s.config)
+	logger.Info("Updated config", "generation", generation, "config", s.config)
 
 	return nil
 }
 
+// configGeneration identifies a configuration by the first 12 hex digits of
+// the SHA-256 of its raw YAML, as recorded in the mutated-by annotation.
+// The names and raw YAML of the config fragments, in merge order, are part
+// of the hash, so that the generation of a configuration without fragments
+// is the hash of its YAML alone.
+func configGeneration(rawConfig []byte, fragments []parsedFragment) string {
+	hash := sha256.New()
+	hash.Write(rawConfig)
+	for _, fragment := range fragments {
+		hash.Write([]byte("\n---\n# " + fragment.name + "\n"))
+		hash.Write(fragment.raw)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
 func validateConfig(config config.Config) error {
 	if config.QueueName == "" {
-		return errors.New("queue name is not set in the PipelineRunCustomDefaulter")
+		return errors.New("queueName: queue name is not set in the PipelineRunCustomDefaulter")
+	}
+	if config.CEL.EvaluationTimeout != nil && config.CEL.EvaluationTimeout.Duration < 0 {
+		return errors.New("cel.evaluationTimeout: evaluation timeout cannot be negative")
 	}
 	return nil
 }
 
+// parseConfig decodes the raw YAML configuration strictly: unknown and
+// duplicate fields fail with their path, e.g. unknown field "cel.expresions".
+// An unversioned configuration, the format before apiVersion and kind were
+// introduced, is converted to the current version.
 func parseConfig(raw []byte) (config.Config, error) {
 	cfg := config.Config{}
-	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+	var typeMeta metav1.TypeMeta
+	if err := yaml.Unmarshal(raw, &typeMeta); err != nil {
 		// Log and keep last-known-good config
 		logger.Error(err, "failed to parse config")
 		return cfg, err
 	}
+
+	switch {
+	case typeMeta.APIVersion == "" && typeMeta.Kind == "":
+		return parseUnversionedConfig(raw)
+	case typeMeta.APIVersion == config.APIVersion && typeMeta.Kind == config.Kind:
+		err := parseStrict(raw, &cfg)
+		if err != nil {
+			logger.Error(err, "failed to parse config")
+		}
+		return cfg, err
+	default:
+		err := fmt.Errorf("unsupported config apiVersion %q and kind %q, expected apiVersion %q and kind %q",
+			typeMeta.APIVersion, typeMeta.Kind, config.APIVersion, config.Kind)
+		logger.Error(err, "failed to parse config")
+		return cfg, err
+	}
+}
+
+// parseUnversionedConfig decodes an unversioned configuration and converts
+// it to the current version. The current version has the same fields, so
+// the conversion only sets apiVersion and kind.
+func parseUnversionedConfig(raw []byte) (config.Config, error) {
+	cfg := config.Config{}
+	if err := parseStrict(raw, &cfg); err != nil {
+		logger.Error(err, "failed to parse config")
+		return cfg, err
+	}
+	cfg.APIVersion = config.APIVersion
+	cfg.Kind = config.Kind
 	return cfg, nil
 }
+
+// parseStrict decodes raw into obj, failing on unknown and duplicate fields.
+func parseStrict(raw []byte, obj any) error {
+	data, err := sigsyaml.YAMLToJSONStrict(raw)
+	if err != nil {
+		return err
+	}
+	strictErrs, err := kjson.UnmarshalStrict(data, obj)
+	if err != nil {
+		return err
+	}
+	if len(strictErrs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(strictErrs...))
+	}
+	return nil
+}
@@ -18,11 +18,16 @@ package v1
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/konflux-ci/tekton-kueue/internal/cel"
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var _ = Describe("Config Store ", func() {
@@ -99,6 +104,624 @@ var _ = Describe("Config Store ", func() {
 			Expect(mutators).To(HaveLen(1))
 			Expect(mutators[0]).To(BeAssignableToTypeOf(&cel.CELMutator{}))
 		})
+		It("should create CEL mutators for named rules", func(ctx context.Context) {
+			configData := `queueName: test-queue
+cel:
+  rules:
+    - name: release-priority
+      description: Release pipelines run first
+      when: 'plrNamespace == "release"'
+      mutations: 'priority("konflux-release")'
+`
+			cfgStore := &ConfigStore{}
+			Expect(cfgStore.Update([]byte(configData))).To(Succeed())
+
+			cfg, mutators := cfgStore.GetConfigAndMutators()
+			Expect(cfg.CEL.Rules).To(HaveLen(1))
+			Expect(cfg.CEL.Rules[0].Name).To(Equal("release-priority"))
+			Expect(mutators).To(HaveLen(1))
+		})
+		It("should reject an expression over the cost limit", func(ctx context.Context) {
+			configData := `queueName: test-queue
+cel:
+  costLimit: 1000
+  expressions:
+    - 'paramArray("build-platforms").map(p, resource(replace(p, "/", "-"), 1))'
+`
+			cfgStore := &ConfigStore{}
+			err := cfgStore.Update([]byte(configData))
+			var costErr *cel.CostLimitError
+			Expect(errors.As(err, &costErr)).To(BeTrue(), "expected *cel.CostLimitError, got %v", err)
+		})
+		It("should reject a rule with a non-bool when condition", func(ctx context.Context) {
+			configData := `queueName: test-queue
+cel:
+  rules:
+    - name: release-priority
+      when: 'plrNamespace'
+      mutations: 'priority("konflux-release")'
+`
+			cfgStore := &ConfigStore{}
+			err := cfgStore.Update([]byte(configData))
+			Expect(err).To(MatchError(ContainSubstring(`failed to compile rule "release-priority"`)))
+		})
+		It("should reject the highestPriority conflict policy without priority classes", func(ctx context.Context) {
+			configData := `queueName: test-queue
+cel:
+  conflictPolicy: highestPriority
+  expressions:
+    - 'priority("konflux-release")'
+`
+			cfgStore := &ConfigStore{}
+			err := cfgStore.Update([]byte(configData))
+			Expect(err).To(MatchError(ContainSubstring(`conflictPolicy "highestPriority" requires priorityClasses`)))
+		})
+		It("should tag the provenance with the config generation", func(ctx context.Context) {
+			configData := `queueName: test-queue
+cel:
+  provenance: true
+  expressions:
+    - 'priority("konflux-release")'
+`
+			cfgStore := &ConfigStore{}
+			Expect(cfgStore.Update([]byte(configData))).To(Succeed())
+			_, mutators := cfgStore.GetConfigAndMutators()
+			Expect(mutators).To(HaveLen(1))
+
+			plr := &tekv1.PipelineRun{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-plr", Namespace: "default"},
+				Spec:       tekv1.PipelineRunSpec{PipelineRef: &tekv1.PipelineRef{Name: "test-pipeline"}},
+			}
+			Expect(mutators[0].Mutate(ctx, plr, cel.Inputs{})).To(Succeed())
+			Expect(plr.Annotations).To(HaveKeyWithValue("kueue.konflux-ci.dev/mutated-by",
+				`{"generation":"`+configGeneration([]byte(configData), nil)+`","labels":{"kueue.x-k8s.io/priority-class":"expressions[0]"}}`))
+		})
+		It("should reject an invalid resource aggregation", func(ctx context.Context) {
+			configData := `queueName: test-queue
+cel:
+  resourceAggregation:
+    memory: average
+  expressions:
+    - 'resource("memory", "4Gi")'
+`
+			cfgStore := &ConfigStore{}
+			err := cfgStore.Update([]byte(configData))
+			Expect(err).To(MatchError(ContainSubstring(`invalid resourceAggregation "average" for resource "memory"`)))
+		})
+		DescribeTable("should reject an invalid literal time window",
+			func(ctx context.Context, expression, errMsg string) {
+				configData := `queueName: test-queue
+cel:
+  expressions:
+    - '` + expression + ` ? [priority("konflux-low")] : []'
+`
+				cfgStore := &ConfigStore{}
+				err := cfgStore.Update([]byte(configData))
+				Expect(err).To(MatchError(ContainSubstring(errMsg)))
+			},
+			Entry("window", `inTimeWindow("Mon-Fry", "UTC")`, `unknown day "Fry"`),
+			Entry("time zone", `inTimeWindow("Mon-Fri", "Europe/Atlantis")`, `unknown time zone "Europe/Atlantis"`),
+		)
+		DescribeTable("should apply the existingResources policy to user-supplied requests",
+			func(ctx context.Context, policy string, expected map[string]string) {
+				configData := `queueName: test-queue
+cel:
+` + policy + `  expressions:
+    - 'resource("cpu", 1)'
+`
+				cfgStore := &ConfigStore{}
+				Expect(cfgStore.Update([]byte(configData))).To(Succeed())
+				_, mutators := cfgStore.GetConfigAndMutators()
+				Expect(mutators).To(HaveLen(1))
+
+				plr := &tekv1.PipelineRun{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-plr",
+						Namespace: "default",
+						Annotations: map[string]string{
+							"kueue.konflux-ci.dev/requests-cpu":    "100",
+							"kueue.konflux-ci.dev/requests-memory": "64Gi",
+						},
+					},
+					Spec: tekv1.PipelineRunSpec{PipelineRef: &tekv1.PipelineRef{Name: "test-pipeline"}},
+				}
+				Expect(mutators[0].Mutate(ctx, plr, cel.Inputs{})).To(Succeed())
+				Expect(plr.Annotations).To(Equal(expected))
+			},
+			Entry("override by default", "", map[string]string{
+				"kueue.konflux-ci.dev/requests-cpu":    "1",
+				"kueue.konflux-ci.dev/requests-memory": "64Gi",
+			}),
+			Entry("aggregate when configured", "  existingResources: aggregate\n", map[string]string{
+				"kueue.konflux-ci.dev/requests-cpu":    "101",
+				"kueue.konflux-ci.dev/requests-memory": "64Gi",
+			}),
+			Entry("remove when configured", "  existingResources: remove\n", map[string]string{
+				"kueue.konflux-ci.dev/requests-cpu": "1",
+			}),
+		)
+		It("should look up values in the configured tables", func(ctx context.Context) {
+			configData := `queueName: test-queue
+tables:
+  tiers:
+    default: gold
+  weights:
+    default: 3
+  platforms:
+    default: [linux/amd64, linux/arm64]
+cel:
+  expressions:
+    - 'priority(lookupOr("tiers", plrNamespace, "bronze"))'
+    - 'resource("weight", lookup("weights", plrNamespace))'
+    - 'lookup("platforms", plrNamespace).map(p, resource(replace(p, "/", "-"), 1))'
+`
+			cfgStore := &ConfigStore{}
+			Expect(cfgStore.Update([]byte(configData))).To(Succeed())
+			_, mutators := cfgStore.GetConfigAndMutators()
+			Expect(mutators).To(HaveLen(1))
+
+			plr := &tekv1.PipelineRun{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-plr", Namespace: "default"},
+				Spec:       tekv1.PipelineRunSpec{PipelineRef: &tekv1.PipelineRef{Name: "test-pipeline"}},
+			}
+			Expect(mutators[0].Mutate(ctx, plr, cel.Inputs{})).To(Succeed())
+			Expect(plr.Labels).To(HaveKeyWithValue("kueue.x-k8s.io/priority-class", "gold"))
+			Expect(plr.Annotations).To(Equal(map[string]string{
+				"kueue.konflux-ci.dev/requests-weight":      "3",
+				"kueue.konflux-ci.dev/requests-linux-amd64": "1",
+				"kueue.konflux-ci.dev/requests-linux-arm64": "1",
+			}))
+		})
+		It("should reject a table mixing value types", func(ctx context.Context) {
+			configData := `queueName: test-queue
+tables:
+  tiers:
+    tenant-a: gold
+    tenant-b: 2
+cel:
+  expressions:
+    - 'priority(lookup("tiers", plrNamespace))'
+`
+			cfgStore := &ConfigStore{}
+			err := cfgStore.Update([]byte(configData))
+			Expect(err).To(MatchError(ContainSubstring(`table "tiers" mixes string and int values`)))
+		})
+		It("should reject a table mixing value types without CEL rules", func(ctx context.Context) {
+			configData := `queueName: test-queue
+tables:
+  tiers:
+    tenant-a: gold
+    tenant-b: 2
+`
+			cfgStore := &ConfigStore{}
+			err := cfgStore.Update([]byte(configData))
+			Expect(err).To(MatchError(ContainSubstring(`table "tiers" mixes string and int values`)))
+			cfg, _ := cfgStore.GetConfigAndMutators()
+			Expect(cfg).To(BeNil())
+		})
+		It("should reject an unsupported table value", func(ctx context.Context) {
+			configData := `queueName: test-queue
+tables:
+  tiers:
+    tenant-a: {name: gold}
+cel:
+  expressions:
+    - 'priority(lookup("tiers", plrNamespace))'
+`
+			cfgStore := &ConfigStore{}
+			err := cfgStore.Update([]byte(configData))
+			Expect(err).To(MatchError(ContainSubstring(`must be a string, an integer or a list of strings`)))
+		})
+		It("should reject a lookup of an unknown table", func(ctx context.Context) {
+			configData := `queueName: test-queue
+tables:
+  tiers:
+    tenant-a: gold
+cel:
+  expressions:
+    - 'priority(lookup("tier", plrNamespace))'
+`
+			cfgStore := &ConfigStore{}
+			err := cfgStore.Update([]byte(configData))
+			Expect(err).To(MatchError(ContainSubstring(`unknown table "tier", must be one of: [tiers]`)))
+		})
+		DescribeTable("should reject an invalid queue route",
+			func(route, expectedErr string) {
+				configData := "queueName: test-queue\nqueueRouting:\n  - " + route + "\n"
+				cfgStore := &ConfigStore{}
+				err := cfgStore.Update([]byte(configData))
+				Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+				cfg, _ := cfgStore.GetConfigAndMutators()
+				Expect(cfg).To(BeNil())
+			},
+			Entry("without a queue name", `namespaces: [release]`, `queueRouting[0]: queue name cannot be empty`),
+			Entry("with an invalid queue name", `{namespaces: [release], queueName: Release_Queue}`, `queueRouting[0]: queue name 'Release_Queue' is invalid`),
+			Entry("without namespaces or selector", `queueName: release-queue`, `queueRouting[0]: namespaces or namespaceSelector must be set`),
+			Entry("with an invalid pattern", `{namespaces: ["release-["], queueName: release-queue}`, `queueRouting[0]: invalid namespace pattern "release-["`),
+			Entry("with an invalid selector",
+				`{namespaceSelector: {matchExpressions: [{key: tier, operator: Within}]}, queueName: release-queue}`,
+				`queueRouting[0]: invalid namespaceSelector`),
+		)
+		It("should expand the configured profiles", func(ctx context.Context) {
+			configData := `queueName: test-queue
+profiles:
+  large:
+    resources:
+      memory: 8Gi
+      linux-amd64: 2
+    priorityClass: konflux-low
+    annotations:
+      example.com/profile: large
+cel:
+  expressions:
+    - 'profile("large")'
+`
+			cfgStore := &ConfigStore{}
+			Expect(cfgStore.Update([]byte(configData))).To(Succeed())
+			_, mutators := cfgStore.GetConfigAndMutators()
+			Expect(mutators).To(HaveLen(1))
+
+			plr := &tekv1.PipelineRun{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-plr", Namespace: "default"},
+				Spec:       tekv1.PipelineRunSpec{PipelineRef: &tekv1.PipelineRef{Name: "test-pipeline"}},
+			}
+			Expect(mutators[0].Mutate(ctx, plr, cel.Inputs{})).To(Succeed())
+			Expect(plr.Labels).To(HaveKeyWithValue("kueue.x-k8s.io/priority-class", "konflux-low"))
+			Expect(plr.Annotations).To(Equal(map[string]string{
+				"kueue.konflux-ci.dev/requests-memory":      "8Gi",
+				"kueue.konflux-ci.dev/requests-linux-amd64": "2",
+				"example.com/profile":                       "large",
+			}))
+		})
+		It("should reject a reference to an unknown profile", func(ctx context.Context) {
+			configData := `queueName: test-queue
+profiles:
+  large:
+    resources:
+      memory: 8Gi
+cel:
+  rules:
+    - name: builds
+      when: 'plrNamespace == "builds"'
+      mutations: 'profile("lagre")'
+`
+			cfgStore := &ConfigStore{}
+			err := cfgStore.Update([]byte(configData))
+			Expect(err).To(MatchError(ContainSubstring(`unknown profile "lagre", must be one of: [large]`)))
+		})
+		DescribeTable("should reject an invalid profile without CEL rules",
+			func(profile, expectedErr string) {
+				cfgStore := &ConfigStore{}
+				Expect(cfgStore.Update([]byte("queueName: test-queue"))).To(Succeed())
+
+				err := cfgStore.Update([]byte("queueName: other-queue\nprofiles:\n  large:\n" + profile))
+				Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+				cfg, _ := cfgStore.GetConfigAndMutators()
+				Expect(cfg.QueueName).To(Equal("test-queue"))
+			},
+			Entry("with a negative quantity", "    resources:\n      memory: -1Gi\n",
+				"profiles.large.resources.memory: resource must be positive (>= 0), got -1Gi"),
+			Entry("with an invalid priority class", "    priorityClass: konflux low\n",
+				"profiles.large.priorityClass: invalid priorityClass"),
+			Entry("with an invalid annotation key", "    annotations:\n      bad key: x\n",
+				"profiles.large.annotations.bad key: annotation key 'bad key' is invalid"),
+		)
+	})
+
+	Context("Config Versioning", func() {
+		It("should load a versioned config", func(ctx context.Context) {
+			configData := `
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: Config
+queueName: test-queue
+`
+			cfgStore := &ConfigStore{}
+			Expect(cfgStore.Update([]byte(configData))).To(Succeed())
+
+			cfg, _ := cfgStore.GetConfigAndMutators()
+			Expect(cfg.APIVersion).To(Equal(config.APIVersion))
+			Expect(cfg.Kind).To(Equal(config.Kind))
+			Expect(cfg.QueueName).To(Equal("test-queue"))
+		})
+
+		It("should convert an unversioned config", func(ctx context.Context) {
+			configData := `
+queueName: test-queue
+cel:
+  expressions:
+    - 'priority("high")'
+`
+			cfgStore := &ConfigStore{}
+			Expect(cfgStore.Update([]byte(configData))).To(Succeed())
+
+			cfg, mutators := cfgStore.GetConfigAndMutators()
+			Expect(cfg.APIVersion).To(Equal(config.APIVersion))
+			Expect(cfg.Kind).To(Equal(config.Kind))
+			Expect(cfg.QueueName).To(Equal("test-queue"))
+			Expect(mutators).To(HaveLen(1))
+		})
+
+		It("should keep the last valid config when an unversioned config has a typo", func(ctx context.Context) {
+			cfgStore := &ConfigStore{}
+			Expect(cfgStore.Update([]byte("queueName: test-queue"))).To(Succeed())
+
+			err := cfgStore.Update([]byte(`
+queueName: other-queue
+multiKueueOveride: true
+`))
+			Expect(err).To(MatchError(ContainSubstring(`unknown field "multiKueueOveride"`)))
+			cfg, _ := cfgStore.GetConfigAndMutators()
+			Expect(cfg.QueueName).To(Equal("test-queue"))
+		})
+
+		DescribeTable("should reject an invalid versioned config",
+			func(configData string, expectedErr string) {
+				cfgStore := &ConfigStore{}
+				err := cfgStore.Update([]byte(configData))
+				Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+			},
+			Entry("unknown nested field", `
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: Config
+queueName: test-queue
+cel:
+  expresions:
+    - 'priority("high")'
+`, `unknown field "cel.expresions"`),
+			Entry("unknown field in an unversioned config", `
+queueName: test-queue
+cel:
+  expresions:
+    - 'priority("high")'
+`, `unknown field "cel.expresions"`),
+			Entry("unknown field in a list", `
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: Config
+queueName: test-queue
+queueRouting:
+  - namespace: [tenant-a]
+    queueName: tenant-a-queue
+`, `unknown field "queueRouting[0].namespace"`),
+			Entry("duplicate field", `
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: Config
+queueName: test-queue
+queueName: other-queue
+`, `"queueName" already set`),
+			Entry("wrong field type", `
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: Config
+queueName: test-queue
+multiKueueOverride: "yes"
+`, "multiKueueOverride"),
+			Entry("unsupported apiVersion", `
+apiVersion: tekton-kueue.konflux-ci.dev/v1
+kind: Config
+queueName: test-queue
+`, `unsupported config apiVersion "tekton-kueue.konflux-ci.dev/v1" and kind "Config"`),
+			Entry("missing kind", `
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+queueName: test-queue
+`, `unsupported config apiVersion "tekton-kueue.konflux-ci.dev/v1alpha1" and kind ""`),
+			Entry("missing queueName", `
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: Config
+`, "queue name is not set"),
+		)
+
+		DescribeTable("should report the path of an invalid field",
+			func(configData string, expectedErr string) {
+				cfgStore := &ConfigStore{}
+				err := cfgStore.Update([]byte("queueName: test-queue\n" + configData))
+				Expect(err).To(MatchError(HavePrefix(expectedErr)))
+			},
+			Entry("rule condition", `
+cel:
+  rules:
+    - name: env
+      mutations: 'label("env", "test")'
+    - name: release
+      when: 'plrNamespace'
+      mutations: 'priority("konflux-release")'
+`, `cel.rules[1].when: failed to compile rule "release": when condition "plrNamespace" must return bool`),
+			Entry("expression", `
+cel:
+  expressions:
+    - 'priority("high")'
+    - 'unknown()'
+`, `cel.expressions[1]: failed to compile expression "unknown()"`),
+			Entry("variable", `
+cel:
+  variables:
+    - name: is-fork
+      expression: 'true'
+  expressions:
+    - 'priority("high")'
+`, `cel.variables[0].name: invalid name "is-fork"`),
+			Entry("evaluation timeout", `
+cel:
+  evaluationTimeout: -1s
+`, "cel.evaluationTimeout: evaluation timeout cannot be negative"),
+			Entry("conflict policy", `
+cel:
+  conflictPolicy: newest
+  expressions:
+    - 'priority("high")'
+`, `cel.conflictPolicy: invalid conflictPolicy "newest"`),
+			Entry("resource aggregation", `
+cel:
+  resourceAggregation:
+    memory: average
+  expressions:
+    - 'resource("memory", "4Gi")'
+`, `cel.resourceAggregation.memory: invalid resourceAggregation "average"`),
+			Entry("table", `
+tables:
+  tiers:
+    tenant-a: gold
+    tenant-b: 2
+`, `tables.tiers.tenant-b: table "tiers" mixes string and int values`),
+			Entry("queue route", `
+queueRouting:
+  - namespaces: [release]
+`, "queueRouting[0]: queue name cannot be empty"),
+		)
+	})
+
+	Context("Config Fragments", func() {
+		const mainConfig = `
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: Config
+queueName: test-queue
+cel:
+  rules:
+    - name: base
+      mutations: 'label("team", "platform")'
+tables:
+  tiers:
+    tenant-a: gold
+queueRouting:
+  - namespaces: [release]
+    queueName: release-queue
+`
+		fragment := func(priority int, rule string) string {
+			return fmt.Sprintf(`
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: ConfigFragment
+priority: %d
+rules:
+  - name: %s
+    mutations: 'label("team", "%s")'
+`, priority, rule, rule)
+		}
+
+		It("should merge the fragments by priority, then by name", func(ctx context.Context) {
+			cfgStore := &ConfigStore{}
+			Expect(cfgStore.Update([]byte(mainConfig),
+				ConfigFragment{Name: "team-b", Data: []byte(fragment(0, "team-b"))},
+				ConfigFragment{Name: "team-a", Data: []byte(fragment(10, "team-a"))},
+				ConfigFragment{Name: "team-c", Data: []byte(fragment(0, "team-c"))},
+			)).To(Succeed())
+
+			cfg, mutators := cfgStore.GetConfigAndMutators()
+			names := []string{}
+			for _, rule := range cfg.CEL.Rules {
+				names = append(names, rule.Name)
+			}
+			Expect(names).To(Equal([]string{"base", "team-b", "team-c", "team-a"}))
+
+			// With the default lastWins conflict policy, the fragment merged
+			// last wins
+			plr := &tekv1.PipelineRun{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec:       tekv1.PipelineRunSpec{PipelineRef: &tekv1.PipelineRef{Name: "test-pipeline"}},
+			}
+			Expect(mutators).To(HaveLen(1))
+			Expect(mutators[0].Mutate(ctx, plr, cel.Inputs{})).To(Succeed())
+			Expect(plr.Labels).To(HaveKeyWithValue("team", "team-a"))
+		})
+
+		It("should merge tables and queue routes", func(ctx context.Context) {
+			cfgStore := &ConfigStore{}
+			Expect(cfgStore.Update([]byte(mainConfig), ConfigFragment{Name: "team-a", Data: []byte(`
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: ConfigFragment
+tables:
+  owners:
+    tenant-a: team-a
+rules:
+  - name: owner
+    mutations: 'annotation("owner", lookupOr("owners", plrNamespace, "nobody"))'
+queueRouting:
+  - namespaces: ["tenant-*"]
+    queueName: tenant-queue
+`)})).To(Succeed())
+
+			cfg, mutators := cfgStore.GetConfigAndMutators()
+			Expect(cfg.Tables).To(HaveKey("tiers"))
+			Expect(cfg.Tables).To(HaveKey("owners"))
+			Expect(cfg.QueueRouting).To(HaveLen(2))
+			Expect(cfg.QueueRouting[1].QueueName).To(Equal("tenant-queue"))
+
+			plr := &tekv1.PipelineRun{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "tenant-a"},
+				Spec:       tekv1.PipelineRunSpec{PipelineRef: &tekv1.PipelineRef{Name: "test-pipeline"}},
+			}
+			for _, mutator := range mutators {
+				Expect(mutator.Mutate(ctx, plr, cel.Inputs{})).To(Succeed())
+			}
+			Expect(plr.Annotations).To(HaveKeyWithValue("owner", "team-a"))
+			_, router, _ := cfgStore.get()
+			queueName, source := router.route(plr, cel.Inputs{})
+			Expect(queueName).To(Equal("tenant-queue"))
+			Expect(source).To(Equal("queueRouting[1]"))
+		})
+
+		It("should keep the generation of a config without fragments", func(ctx context.Context) {
+			Expect(configGeneration([]byte(mainConfig), nil)).To(Equal(configGeneration([]byte(mainConfig), []parsedFragment{})))
+			fragments, err := parseFragments([]ConfigFragment{{Name: "team-a", Data: []byte(fragment(0, "team-a"))}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configGeneration([]byte(mainConfig), fragments)).NotTo(Equal(configGeneration([]byte(mainConfig), nil)))
+		})
+
+		DescribeTable("should reject invalid fragments",
+			func(fragments []ConfigFragment, expectedErr string) {
+				cfgStore := &ConfigStore{}
+				Expect(cfgStore.Update([]byte("queueName: test-queue"))).To(Succeed())
+
+				err := cfgStore.Update([]byte(mainConfig), fragments...)
+				Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+
+				// The last valid config is kept
+				cfg, _ := cfgStore.GetConfigAndMutators()
+				Expect(cfg.CEL.Rules).To(BeEmpty())
+			},
+			Entry("rule defined by the main config",
+				[]ConfigFragment{{Name: "team-a", Data: []byte(fragment(0, "base"))}},
+				`config fragment "team-a": rule "base" is already defined by the main config`),
+			Entry("rule defined by another fragment",
+				[]ConfigFragment{
+					{Name: "team-b", Data: []byte(fragment(0, "shared"))},
+					{Name: "team-a", Data: []byte(fragment(0, "shared"))},
+				},
+				`config fragment "team-b": rule "shared" is already defined by config fragment "team-a"`),
+			Entry("table defined by the main config",
+				[]ConfigFragment{{Name: "team-a", Data: []byte(`
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: ConfigFragment
+tables:
+  tiers:
+    tenant-b: silver
+`)}},
+				`config fragment "team-a": table "tiers" is already defined by the main config`),
+			Entry("invalid queue route",
+				[]ConfigFragment{{Name: "team-a", Data: []byte(`
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: ConfigFragment
+queueRouting:
+  - queueName: tenant-queue
+`)}},
+				`config fragment "team-a": queueRouting[0]: namespaces or namespaceSelector must be set`),
+			Entry("unknown field",
+				[]ConfigFragment{{Name: "team-a", Data: []byte(`
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: ConfigFragment
+queueName: other-queue
+`)}},
+				`config fragment "team-a": invalid config: unknown field "queueName"`),
+			Entry("missing apiVersion and kind",
+				[]ConfigFragment{{Name: "team-a", Data: []byte("rules: []")}},
+				`config fragment "team-a": unsupported apiVersion "" and kind ""`),
+			Entry("invalid rule",
+				[]ConfigFragment{{Name: "team-a", Data: []byte(`
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: ConfigFragment
+rules:
+  - name: broken
+    mutations: 'label("team",'
+`)}},
+				`broken`),
+		)
 	})
 
 	Context("Invalid Configuration", func() {
@@ -120,7 +120,7 @@ var _ = BeforeSuite(func() {
 	err = cfgStore.Update([]byte(rawConfig))
 	Expect(err).NotTo(HaveOccurred())
 
-	defaulter, err := v1.NewCustomDefaulter(cfgStore)
+	defaulter, err := v1.NewCustomDefaulter(cfgStore, nil)
 	Expect(err).NotTo(HaveOccurred())
 
 	err = v1.SetupPipelineRunWebhookWithManager(mgr, defaulter)
@@ -0,0 +1,66 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NamespaceGetter looks up the Namespace a PipelineRun is created in, so that
+// its labels and annotations can be exposed to CEL expressions.
+type NamespaceGetter interface {
+	// GetNamespace returns the Namespace with the given name, or nil if it
+	// does not exist.
+	GetNamespace(ctx context.Context, name string) (metav1.Object, error)
+}
+
+// NewNamespaceGetter returns a NamespaceGetter backed by the given reader,
+// typically the manager's informer cache. Only Namespace metadata is read,
+// so the cache holds a metadata-only informer for Namespaces.
+func NewNamespaceGetter(reader client.Reader) NamespaceGetter {
+	return &cachedNamespaceGetter{reader: reader}
+}
+
+type cachedNamespaceGetter struct {
+	reader client.Reader
+}
+
+func (g *cachedNamespaceGetter) GetNamespace(ctx context.Context, name string) (metav1.Object, error) {
+	ns := NewNamespaceMetadata()
+	if err := g.reader.Get(ctx, client.ObjectKey{Name: name}, ns); err != nil {
+		if k8serrors.IsNotFound(err) {
+			// The cache may lag behind a freshly created Namespace; expressions
+			// then see empty labels and annotations.
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ns, nil
+}
+
+// NewNamespaceMetadata returns an empty metadata-only Namespace object, as
+// stored by the webhook's cache.
+func NewNamespaceMetadata() *metav1.PartialObjectMetadata {
+	ns := &metav1.PartialObjectMetadata{}
+	ns.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
+	return ns
+}
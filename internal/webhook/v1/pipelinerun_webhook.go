@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/konflux-ci/tekton-kueue/internal/cel"
@@ -53,8 +54,10 @@ func SetupPipelineRunWebhookWithManager(mgr ctrl.Manager, defaulter admission.Cu
 }
 
 // allowedPatchPrefixes lists the JSON Pointer prefixes for fields that the
-// webhook intentionally modifies. Any patch outside this allowlist is a
-// side-effect of Go struct round-tripping and gets dropped.
+// webhook intentionally modifies. This covers both add/replace and remove
+// operations, the latter coming from CEL removeLabel() and removeAnnotation()
+// mutations. Any patch outside this allowlist is a side-effect of Go struct
+// round-tripping and gets dropped.
 var allowedPatchPrefixes = []string{
 	"/metadata/labels",
 	"/metadata/annotations",
@@ -126,7 +129,7 @@ func logConstructor(base logr.Logger, req *admission.Request) logr.Logger {
 	return log
 }
 
-// +kubebuilder:webhook:path=/mutate-tekton-dev-v1-pipelinerun,mutating=true,failurePolicy=fail,sideEffects=None,groups=tekton.dev,resources=pipelineruns,verbs=create,versions=v1,name=pipelinerun-kueue-defaulter.tekton-kueue.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-tekton-dev-v1-pipelinerun,mutating=true,failurePolicy=fail,sideEffects=None,groups=tekton.dev,resources=pipelineruns,verbs=create,versions=v1,name=pipelinerun-kueue-defaulter.tekton-kueue.io,admissionReviewVersions=v1,timeoutSeconds=10
 
 // pipelineRunCustomDefaulter is the webhook handler that intercepts PipelineRun
 // creation requests. It suspends each PipelineRun (Pending), assigns it to a
@@ -135,11 +138,32 @@ func logConstructor(base logr.Logger, req *admission.Request) logr.Logger {
 // PipelineRuns from bypassing Kueue.
 type pipelineRunCustomDefaulter struct {
 	configStore *ConfigStore
+	namespaces  NamespaceGetter
+	now         func() time.Time
 }
 
-func NewCustomDefaulter(configStore *ConfigStore) (webhook.CustomDefaulter, error) {
+// DefaulterOption configures optional behavior of the PipelineRun defaulter.
+type DefaulterOption func(*pipelineRunCustomDefaulter)
+
+// WithNow sets the clock that provides the time exposed to CEL expressions.
+// It defaults to time.Now; the mutate CLI fixes it for reproducible results.
+func WithNow(now func() time.Time) DefaulterOption {
+	return func(d *pipelineRunCustomDefaulter) {
+		d.now = now
+	}
+}
+
+// NewCustomDefaulter creates the PipelineRun defaulter. namespaces is used to
+// expose the PipelineRun's Namespace to CEL expressions; it may be nil, in
+// which case expressions see a Namespace without labels or annotations.
+func NewCustomDefaulter(configStore *ConfigStore, namespaces NamespaceGetter, opts ...DefaulterOption) (webhook.CustomDefaulter, error) {
 	defaulter := &pipelineRunCustomDefaulter{
 		configStore: configStore,
+		namespaces:  namespaces,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(defaulter)
 	}
 	return defaulter, nil
 }
@@ -156,19 +180,40 @@ func (d *pipelineRunCustomDefaulter) Default(ctx context.Context, obj runtime.Ob
 	if plr.Labels == nil {
 		plr.Labels = make(map[string]string)
 	}
-	config, mutators := d.configStore.GetConfigAndMutators()
-	if _, exists := plr.Labels[common.QueueLabel]; !exists {
-		plr.Labels[common.QueueLabel] = config.QueueName
-	}
+	config, router, mutators := d.configStore.get()
 	if config.MultiKueueOverride {
 		plr.Spec.ManagedBy = ptr.To(common.ManagedByMultiKueueLabel)
 	}
+	inputs, err := d.inputs(ctx, plr, router, mutators)
+	if err != nil {
+		return err
+	}
+	// The queue label is defaulted before the mutators run, so that CEL
+	// expressions see the queue the PipelineRun is assigned to unless they
+	// change it. The queue is resolved with the following precedence:
+	//  1. a queue() CEL mutation
+	//  2. a queue label supplied by the user
+	//  3. the first matching queueRouting entry
+	//  4. the configured queueName
+	// The source of a defaulted queue label is passed on for the provenance.
+	if _, exists := plr.Labels[common.QueueLabel]; !exists {
+		queueName, source := router.route(plr, inputs)
+		if source == "" {
+			queueName, source = config.QueueName, "queueName"
+		}
+		plr.Labels[common.QueueLabel] = queueName
+		inputs.QueueSource = source
+	}
 	for _, mutator := range mutators {
-		if err := mutator.Mutate(plr); err != nil {
+		if err := mutator.Mutate(ctx, plr, inputs); err != nil {
 			var validationErr *cel.ValidationError
 			if errors.As(err, &validationErr) {
 				return k8serrors.NewBadRequest(validationErr.Error())
 			}
+			var costErr *cel.CostLimitError
+			if errors.As(err, &costErr) && costErr.Timeout {
+				return k8serrors.NewTimeoutError(err.Error(), 0)
+			}
 			var evaluationErr *cel.EvaluationError
 			if errors.As(err, &evaluationErr) {
 				return k8serrors.NewInternalError(evaluationErr)
@@ -179,3 +224,19 @@ func (d *pipelineRunCustomDefaulter) Default(ctx context.Context, obj runtime.Ob
 
 	return nil
 }
+
+// inputs collects the admission-scoped data exposed to the queue router and
+// the mutators. The Namespace is only looked up when there is one of them
+// to consume it.
+func (d *pipelineRunCustomDefaulter) inputs(ctx context.Context, plr *tekv1.PipelineRun, router *queueRouter, mutators []PipelineRunMutator) (cel.Inputs, error) {
+	inputs := cel.Inputs{Now: d.now()}
+	if (router == nil && len(mutators) == 0) || d.namespaces == nil {
+		return inputs, nil
+	}
+	ns, err := d.namespaces.GetNamespace(ctx, plr.Namespace)
+	if err != nil {
+		return inputs, k8serrors.NewInternalError(fmt.Errorf("failed to get namespace %q: %w", plr.Namespace, err))
+	}
+	inputs.Namespace = ns
+	return inputs, nil
+}
@@ -30,8 +30,14 @@ import (
 	. "github.com/onsi/gomega"
 	tektondevv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -70,7 +76,7 @@ var _ = Describe("PipelineRun Webhook", func() {
 				}
 
 				var err error
-				defaulter, err = NewCustomDefaulter(cfgStore)
+				defaulter, err = NewCustomDefaulter(cfgStore, nil)
 				Expect(err).NotTo(HaveOccurred())
 				err = defaulter.Default(ctx, plr)
 				Expect(err).NotTo(HaveOccurred())
@@ -89,7 +95,7 @@ var _ = Describe("PipelineRun Webhook", func() {
 					config: cfg,
 				}
 				var err error
-				defaulter, err = NewCustomDefaulter(cfgStore)
+				defaulter, err = NewCustomDefaulter(cfgStore, nil)
 				Expect(err).NotTo(HaveOccurred())
 				err = defaulter.Default(ctx, plr)
 				Expect(err).NotTo(HaveOccurred())
@@ -105,13 +111,245 @@ var _ = Describe("PipelineRun Webhook", func() {
 				config: cfg,
 			}
 			var err error
-			defaulter, err = NewCustomDefaulter(cfgStore)
+			defaulter, err = NewCustomDefaulter(cfgStore, nil)
 			Expect(err).NotTo(HaveOccurred())
 			err = defaulter.Default(ctx, plr)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(plr.Labels[common.QueueLabel]).To(Equal("test-queue"))
 		})
 
+		Context("when resolving the queue", func() {
+			newDefaulter := func(expressions ...string) webhook.CustomDefaulter {
+				cfgStore := &ConfigStore{
+					config: &config.Config{QueueName: "test-queue"},
+				}
+				if len(expressions) > 0 {
+					programs, err := cel.CompileCELPrograms(expressions)
+					Expect(err).NotTo(HaveOccurred())
+					cfgStore.mutators = []PipelineRunMutator{cel.NewCELMutator(programs)}
+				}
+				d, err := NewCustomDefaulter(cfgStore, nil)
+				Expect(err).NotTo(HaveOccurred())
+				return d
+			}
+
+			It("should prefer a queue() mutation over the configured queue name", func(ctx context.Context) {
+				Expect(newDefaulter(`queue("cel-queue")`).Default(ctx, plr)).To(Succeed())
+				Expect(plr.Labels[common.QueueLabel]).To(Equal("cel-queue"))
+			})
+
+			It("should prefer a queue() mutation over a user supplied queue label", func(ctx context.Context) {
+				plr.Labels = map[string]string{common.QueueLabel: "user-queue"}
+				Expect(newDefaulter(`queue("cel-queue")`).Default(ctx, plr)).To(Succeed())
+				Expect(plr.Labels[common.QueueLabel]).To(Equal("cel-queue"))
+			})
+
+			It("should prefer a user supplied queue label over the configured queue name", func(ctx context.Context) {
+				plr.Labels = map[string]string{common.QueueLabel: "user-queue"}
+				Expect(newDefaulter(`label("env", "test")`).Default(ctx, plr)).To(Succeed())
+				Expect(plr.Labels[common.QueueLabel]).To(Equal("user-queue"))
+			})
+
+			It("should fall back to the configured queue name when no queue() mutation matches", func(ctx context.Context) {
+				Expect(newDefaulter(`plrNamespace == "release" ? [queue("release-queue")] : []`).Default(ctx, plr)).To(Succeed())
+				Expect(plr.Labels[common.QueueLabel]).To(Equal("test-queue"))
+			})
+
+			It("should expose the defaulted queue label to CEL", func(ctx context.Context) {
+				expression := `annotation("seen-queue", pipelineRun.metadata.labels["kueue.x-k8s.io/queue-name"])`
+				Expect(newDefaulter(expression).Default(ctx, plr)).To(Succeed())
+				Expect(plr.Annotations["seen-queue"]).To(Equal("test-queue"))
+				Expect(plr.Labels[common.QueueLabel]).To(Equal("test-queue"))
+			})
+
+			It("should expose the user supplied queue label to CEL", func(ctx context.Context) {
+				plr.Labels = map[string]string{common.QueueLabel: "user-queue"}
+				expression := `annotation("seen-queue", pipelineRun.metadata.labels["kueue.x-k8s.io/queue-name"])`
+				Expect(newDefaulter(expression).Default(ctx, plr)).To(Succeed())
+				Expect(plr.Annotations["seen-queue"]).To(Equal("user-queue"))
+			})
+		})
+
+		Context("when routing queues by namespace", func() {
+			const routingConfig = `queueName: test-queue
+queueRouting:
+  - namespaces: [release, "release-*"]
+    queueName: release-queue
+  - namespaceSelector:
+      matchLabels:
+        tier: gold
+    queueName: gold-queue
+  - namespaces: ["tenant-*"]
+    namespaceSelector:
+      matchExpressions:
+        - {key: tier, operator: In, values: [gold, silver]}
+    queueName: silver-queue
+`
+			newDefaulter := func(configData string) webhook.CustomDefaulter {
+				cfgStore := &ConfigStore{}
+				Expect(cfgStore.Update([]byte(configData))).To(Succeed())
+				namespaces := NewNamespaceGetter(fake.NewClientBuilder().WithObjects(
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-gold", Labels: map[string]string{"tier": "gold"}}},
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-silver", Labels: map[string]string{"tier": "silver"}}},
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "release-prod", Labels: map[string]string{"tier": "gold"}}},
+				).Build())
+				d, err := NewCustomDefaulter(cfgStore, namespaces)
+				Expect(err).NotTo(HaveOccurred())
+				return d
+			}
+
+			DescribeTable("should assign the queue of the first matching route",
+				func(ctx context.Context, namespace, expectedQueue string) {
+					plr.Namespace = namespace
+					Expect(newDefaulter(routingConfig).Default(ctx, plr)).To(Succeed())
+					Expect(plr.Labels[common.QueueLabel]).To(Equal(expectedQueue))
+				},
+				Entry("by name", "release", "release-queue"),
+				Entry("by glob before a matching selector", "release-prod", "release-queue"),
+				Entry("by label selector", "tenant-gold", "gold-queue"),
+				Entry("by glob and label selector", "tenant-silver", "silver-queue"),
+				Entry("falling back to the configured queue name", "tenant-bronze", "test-queue"),
+			)
+
+			It("should prefer a user supplied queue label over the routes", func(ctx context.Context) {
+				plr.Namespace = "release"
+				plr.Labels = map[string]string{common.QueueLabel: "user-queue"}
+				Expect(newDefaulter(routingConfig).Default(ctx, plr)).To(Succeed())
+				Expect(plr.Labels[common.QueueLabel]).To(Equal("user-queue"))
+			})
+
+			It("should expose the routed queue label to CEL rules", func(ctx context.Context) {
+				plr.Namespace = "release"
+				configData := routingConfig + `cel:
+  rules:
+    - name: release-priority
+      when: 'pipelineRun.metadata.labels["kueue.x-k8s.io/queue-name"] == "release-queue"'
+      mutations: 'priority("konflux-release")'
+`
+				Expect(newDefaulter(configData).Default(ctx, plr)).To(Succeed())
+				Expect(plr.Labels).To(HaveKeyWithValue(common.PriorityClassLabel, "konflux-release"))
+				Expect(plr.Labels[common.QueueLabel]).To(Equal("release-queue"))
+			})
+
+			DescribeTable("should record the source of the queue label in the provenance",
+				func(ctx context.Context, namespace, expression, expectedLabels string) {
+					plr.Namespace = namespace
+					configData := routingConfig + `cel:
+  provenance: true
+  expressions:
+    - '` + expression + `'
+`
+					Expect(newDefaulter(configData).Default(ctx, plr)).To(Succeed())
+					Expect(plr.Annotations).To(HaveKeyWithValue(common.MutatedByAnnotation,
+						HaveSuffix(`"annotations":{"owner":"expressions[0]"}}`)))
+					Expect(plr.Annotations[common.MutatedByAnnotation]).To(ContainSubstring(expectedLabels))
+				},
+				Entry("by a route", "release", `annotation("owner", "team-a")`,
+					`"labels":{"kueue.x-k8s.io/queue-name":"queueRouting[0]"}`),
+				Entry("by the configured queue name", "tenant-bronze", `annotation("owner", "team-a")`,
+					`"labels":{"kueue.x-k8s.io/queue-name":"queueName"}`),
+				Entry("by a queue() mutation", "release", `[queue("cel-queue"), annotation("owner", "team-a")]`,
+					`"labels":{"kueue.x-k8s.io/queue-name":"expressions[0]"}`),
+			)
+
+			It("should not record a user supplied queue label in the provenance", func(ctx context.Context) {
+				plr.Namespace = "release"
+				plr.Labels = map[string]string{common.QueueLabel: "user-queue"}
+				configData := routingConfig + `cel:
+  provenance: true
+  expressions:
+    - 'annotation("owner", "team-a")'
+`
+				Expect(newDefaulter(configData).Default(ctx, plr)).To(Succeed())
+				Expect(plr.Annotations[common.MutatedByAnnotation]).NotTo(ContainSubstring(`"labels"`))
+			})
+
+			It("should prefer a queue() mutation over the routes", func(ctx context.Context) {
+				plr.Namespace = "release"
+				configData := routingConfig + `cel:
+  expressions:
+    - 'queue("cel-queue")'
+`
+				Expect(newDefaulter(configData).Default(ctx, plr)).To(Succeed())
+				Expect(plr.Labels[common.QueueLabel]).To(Equal("cel-queue"))
+			})
+
+			DescribeTable("should compare shadow queue() rules with the routed queue",
+				func(ctx context.Context, namespace, matched string) {
+					plr.Namespace = namespace
+					configData := routingConfig + `cel:
+  rules:
+    - name: shadow-release-queue
+      mutations: 'queue("release-queue")'
+      shadow: true
+`
+					before := shadowEvaluations("shadow-release-queue", matched)
+					Expect(newDefaulter(configData).Default(ctx, plr)).To(Succeed())
+					Expect(shadowEvaluations("shadow-release-queue", matched)).To(Equal(before + 1))
+				},
+				Entry("by a route", "release", "true"),
+				Entry("by the configured queue name", "tenant-bronze", "false"),
+			)
+		})
+
+		Context("when exposing the namespace", func() {
+			const expression = `label("tenant", "tenant" in namespaceObject.labels ? namespaceObject.labels["tenant"] : "none")`
+
+			newDefaulter := func(namespaces NamespaceGetter) webhook.CustomDefaulter {
+				programs, err := cel.CompileCELPrograms([]string{expression})
+				Expect(err).NotTo(HaveOccurred())
+				cfgStore := &ConfigStore{
+					config:   &config.Config{QueueName: "test-queue"},
+					mutators: []PipelineRunMutator{cel.NewCELMutator(programs)},
+				}
+				d, err := NewCustomDefaulter(cfgStore, namespaces)
+				Expect(err).NotTo(HaveOccurred())
+				return d
+			}
+
+			BeforeEach(func() {
+				plr.Namespace = "tenant-ns"
+			})
+
+			It("should pass the namespace labels to CEL", func(ctx context.Context) {
+				namespace := &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "tenant-ns",
+						Labels: map[string]string{"tenant": "team-a"},
+					},
+				}
+				namespaces := NewNamespaceGetter(fake.NewClientBuilder().WithObjects(namespace).Build())
+
+				Expect(newDefaulter(namespaces).Default(ctx, plr)).To(Succeed())
+				Expect(plr.Labels["tenant"]).To(Equal("team-a"))
+			})
+
+			It("should expose an empty namespace when it is not found", func(ctx context.Context) {
+				namespaces := NewNamespaceGetter(fake.NewClientBuilder().Build())
+
+				Expect(newDefaulter(namespaces).Default(ctx, plr)).To(Succeed())
+				Expect(plr.Labels["tenant"]).To(Equal("none"))
+			})
+
+			It("should expose an empty namespace without a namespace getter", func(ctx context.Context) {
+				Expect(newDefaulter(nil).Default(ctx, plr)).To(Succeed())
+				Expect(plr.Labels["tenant"]).To(Equal("none"))
+			})
+
+			It("should fail when the namespace lookup fails", func(ctx context.Context) {
+				namespaces := NewNamespaceGetter(fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+					Get: func(context.Context, client.WithWatch, client.ObjectKey, client.Object, ...client.GetOption) error {
+						return fmt.Errorf("cache unavailable")
+					},
+				}).Build())
+
+				err := newDefaulter(namespaces).Default(ctx, plr)
+				Expect(err).To(HaveOccurred())
+				Expect(errors.IsInternalError(err)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("cache unavailable"))
+			})
+		})
+
 		It("should accept a valid PipelineRun with pipelineRef", func(ctx context.Context) {
 			plrWithRef := &tektondevv1.PipelineRun{
 				Spec: tektondevv1.PipelineRunSpec{
@@ -128,7 +366,7 @@ var _ = Describe("PipelineRun Webhook", func() {
 				config: cfg,
 			}
 			var err error
-			defaulter, err = NewCustomDefaulter(cfgStore)
+			defaulter, err = NewCustomDefaulter(cfgStore, nil)
 			Expect(err).NotTo(HaveOccurred())
 			err = defaulter.Default(ctx, plrWithRef)
 			Expect(err).NotTo(HaveOccurred())
@@ -167,7 +405,7 @@ var _ = Describe("PipelineRun Webhook", func() {
 				config: cfg,
 			}
 			var err error
-			defaulter, err = NewCustomDefaulter(cfgStore)
+			defaulter, err = NewCustomDefaulter(cfgStore, nil)
 			Expect(err).NotTo(HaveOccurred())
 			err = defaulter.Default(ctx, plrWithSpec)
 			Expect(err).NotTo(HaveOccurred())
@@ -194,7 +432,7 @@ var _ = Describe("PipelineRun Webhook", func() {
 					cel.NewCELMutator(programs),
 				},
 			}
-			defaulter, err = NewCustomDefaulter(cfgStore)
+			defaulter, err = NewCustomDefaulter(cfgStore, nil)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(defaulter.Default(ctx, invalidPlr)).
 				Error().
@@ -264,7 +502,7 @@ var _ = Describe("PipelineRun Webhook", func() {
 				config: cfg,
 			}
 			var err error
-			defaulter, err = NewCustomDefaulter(cfgStore)
+			defaulter, err = NewCustomDefaulter(cfgStore, nil)
 			Expect(err).NotTo(HaveOccurred())
 			err = defaulter.Default(ctx, plrWithParamNoType)
 			Expect(err).NotTo(HaveOccurred())
@@ -291,7 +529,7 @@ var _ = Describe("PipelineRun Webhook", func() {
 					cel.NewCELMutator(programs),
 				},
 			}
-			defaulter, err = NewCustomDefaulter(cfgStore)
+			defaulter, err = NewCustomDefaulter(cfgStore, nil)
 			Expect(err).NotTo(HaveOccurred())
 			// we expect to see a 400 Bad Request here
 			Expect(defaulter.Default(ctx, &pipelineRun)).
@@ -310,15 +548,15 @@ var _ = Describe("PipelineRun Webhook", func() {
 				},
 			}
 
-			// Use a CEL expression that accesses a non-existent field, causing a runtime error
-			programs, err := cel.CompileCELPrograms([]string{`annotation("key", pipelineRun.doesNotExist)`})
+			// Use a CEL expression that accesses a missing label, causing a runtime error
+			programs, err := cel.CompileCELPrograms([]string{`annotation("key", pipelineRun.metadata.labels["does-not-exist"])`})
 			Expect(err).NotTo(HaveOccurred())
 
 			cfgStore := &ConfigStore{
 				config:   &config.Config{QueueName: "test-queue"},
 				mutators: []PipelineRunMutator{cel.NewCELMutator(programs)},
 			}
-			defaulter, err = NewCustomDefaulter(cfgStore)
+			defaulter, err = NewCustomDefaulter(cfgStore, nil)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(defaulter.Default(ctx, validPlr)).
 				Error().
@@ -327,6 +565,31 @@ var _ = Describe("PipelineRun Webhook", func() {
 					MatchError(ContainSubstring("CEL evaluation failed"))))
 		})
 
+		It("should return a TimeoutError when CEL evaluation times out", func(ctx context.Context) {
+			validPlr := &tektondevv1.PipelineRun{
+				Spec: tektondevv1.PipelineRunSpec{
+					PipelineRef: &tektondevv1.PipelineRef{
+						Name: "my-pipeline",
+					},
+				},
+			}
+
+			cfgStore := &ConfigStore{}
+			Expect(cfgStore.Update([]byte(`queueName: test-queue
+cel:
+  evaluationTimeout: 1ns
+  expressions:
+    - 'label("env", "test")'
+`))).To(Succeed())
+			defaulter, err := NewCustomDefaulter(cfgStore, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(defaulter.Default(ctx, validPlr)).
+				Error().
+				To(And(
+					Satisfy(errors.IsTimeout),
+					MatchError(ContainSubstring("CEL evaluation timed out"))))
+		})
+
 		It("should reject a non-pipelinerun object", func(ctx context.Context) {
 			cfg := &config.Config{
 				QueueName: "test-queue",
@@ -335,7 +598,7 @@ var _ = Describe("PipelineRun Webhook", func() {
 				config: cfg,
 			}
 			var err error
-			defaulter, err = NewCustomDefaulter(cfgStore)
+			defaulter, err = NewCustomDefaulter(cfgStore, nil)
 			Expect(err).NotTo(HaveOccurred())
 			// we don't expect to see this in practice, but better safe than sorry
 			Expect(defaulter.Default(ctx, &tektondevv1.Pipeline{})).
@@ -411,7 +674,7 @@ var _ = Describe("Zero-value field leak (issue #319)", func() {
 
 	It("raw CustomDefaulter leaks zero-value struct fields into patches", func(ctx context.Context) {
 
-		defaulter, err := NewCustomDefaulter(cfgStore)
+		defaulter, err := NewCustomDefaulter(cfgStore, nil)
 		Expect(err).NotTo(HaveOccurred())
 		unfiltered := admission.WithCustomDefaulter(scheme, &tektondevv1.PipelineRun{}, defaulter)
 		resp := unfiltered.Handle(ctx, makeAdmissionRequest(minimalPipelineRunJSON))
@@ -436,7 +699,7 @@ var _ = Describe("Zero-value field leak (issue #319)", func() {
 	})
 
 	It("patchFilteringWebhook strips the leaked fields", func(ctx context.Context) {
-		defaulter, err := NewCustomDefaulter(cfgStore)
+		defaulter, err := NewCustomDefaulter(cfgStore, nil)
 		Expect(err).NotTo(HaveOccurred())
 
 		inner := admission.WithCustomDefaulter(scheme, &tektondevv1.PipelineRun{}, defaulter)
@@ -459,11 +722,73 @@ var _ = Describe("Zero-value field leak (issue #319)", func() {
 		}
 	})
 
+	It("patchFilteringWebhook keeps the remove operations of CEL removals", func(ctx context.Context) {
+		programs, err := cel.CompileCELPrograms([]string{
+			`[removeLabel("kueue.x-k8s.io/priority-class"), removeAnnotation("kueue.konflux-ci.dev/requests-aws-vm-x")]`,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		cfgStore.mutators = []PipelineRunMutator{cel.NewCELMutator(programs)}
+		defaulter, err := NewCustomDefaulter(cfgStore, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		inner := admission.WithCustomDefaulter(scheme, &tektondevv1.PipelineRun{}, defaulter)
+		filtered := &patchFilteringWebhook{inner: inner}
+
+		resp := filtered.Handle(ctx, makeAdmissionRequest([]byte(`{
+			"apiVersion": "tekton.dev/v1",
+			"kind": "PipelineRun",
+			"metadata": {
+				"name": "test-plr",
+				"namespace": "default",
+				"labels": {"kueue.x-k8s.io/priority-class": "konflux-release"},
+				"annotations": {"kueue.konflux-ci.dev/requests-aws-vm-x": "4"}
+			},
+			"spec": {
+				"pipelineRef": {"name": "test-pipeline"}
+			}
+		}`)))
+		Expect(resp.Allowed).To(BeTrue())
+
+		var removed []string
+		for _, p := range resp.Patches {
+			if p.Operation == "remove" {
+				removed = append(removed, p.Path)
+			}
+		}
+		Expect(removed).To(ConsistOf(
+			"/metadata/labels/kueue.x-k8s.io~1priority-class",
+			"/metadata/annotations",
+		))
+	})
+
+	It("patchFilteringWebhook keeps the provenance annotation", func(ctx context.Context) {
+		programs, err := cel.CompileCELPrograms([]string{`priority("konflux-release")`})
+		Expect(err).NotTo(HaveOccurred())
+		cfgStore.mutators = []PipelineRunMutator{cel.NewCELMutator(programs, cel.WithProvenance("0123456789ab"))}
+		defaulter, err := NewCustomDefaulter(cfgStore, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		inner := admission.WithCustomDefaulter(scheme, &tektondevv1.PipelineRun{}, defaulter)
+		filtered := &patchFilteringWebhook{inner: inner}
+
+		resp := filtered.Handle(ctx, makeAdmissionRequest(minimalPipelineRunJSON))
+		Expect(resp.Allowed).To(BeTrue())
+
+		var annotations any
+		for _, p := range resp.Patches {
+			if p.Path == "/metadata/annotations" {
+				annotations = p.Value
+			}
+		}
+		Expect(annotations).To(HaveKeyWithValue("kueue.konflux-ci.dev/mutated-by",
+			`{"generation":"0123456789ab","labels":{"kueue.x-k8s.io/priority-class":"expressions[0]","kueue.x-k8s.io/queue-name":"queueName"}}`))
+	})
+
 	// This Test validates the case when PipelineRun Contains all the fields and Webhook is not expected to apply Any patch.
 	// In Such Scenario Handler webhook should set the Patch and PatchType to Nil
 	// Both these values should be sync otherwise Kubernetes will not be able to process the PipelineRun.
 	It("patchFilteringWebhook sets Patch and PatchType to nil when there is nothing to patch", func(ctx context.Context) {
-		defaulter, err := NewCustomDefaulter(cfgStore)
+		defaulter, err := NewCustomDefaulter(cfgStore, nil)
 		Expect(err).NotTo(HaveOccurred())
 
 		inner := admission.WithCustomDefaulter(scheme, &tektondevv1.PipelineRun{}, defaulter)
@@ -477,3 +802,25 @@ var _ = Describe("Zero-value field leak (issue #319)", func() {
 		Expect(resp.PatchType).To(BeNil())
 	})
 })
+
+// shadowEvaluations reads tekton_kueue_cel_shadow_evaluations_total for a
+// rule from the controller-runtime registry.
+func shadowEvaluations(rule, matched string) float64 {
+	families, err := metrics.Registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() != "tekton_kueue_cel_shadow_evaluations_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["rule"] == rule && labels["matched"] == matched {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/konflux-ci/tekton-kueue/internal/cel"
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
+	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// queueRouter applies the queueRouting of the config. The webhook consults
+// it before the CEL mutators run and only if the user didn't set the queue
+// label, so that a queue() mutation still takes precedence and the
+// configured queueName remains the last fallback.
+type queueRouter struct {
+	routes []queueRoute
+}
+
+// queueRoute is a validated config.QueueRoute.
+type queueRoute struct {
+	namespaces []string
+	// selector is nil if the route has no namespaceSelector
+	selector  labels.Selector
+	queueName string
+}
+
+// newQueueRouter validates the routes, so that an invalid route fails the
+// config reload rather than the admission.
+func newQueueRouter(routes []config.QueueRoute) (*queueRouter, error) {
+	router := &queueRouter{routes: make([]queueRoute, 0, len(routes))}
+	for i, route := range routes {
+		if err := cel.ValidateQueueName(route.QueueName); err != nil {
+			return nil, fmt.Errorf("queueRouting[%d]: %w", i, err)
+		}
+		if len(route.Namespaces) == 0 && route.NamespaceSelector == nil {
+			return nil, fmt.Errorf("queueRouting[%d]: namespaces or namespaceSelector must be set", i)
+		}
+		for _, pattern := range route.Namespaces {
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				return nil, fmt.Errorf("queueRouting[%d]: invalid namespace pattern %q", i, pattern)
+			}
+		}
+		compiled := queueRoute{namespaces: route.Namespaces, queueName: route.QueueName}
+		if route.NamespaceSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(route.NamespaceSelector)
+			if err != nil {
+				return nil, fmt.Errorf("queueRouting[%d]: invalid namespaceSelector: %w", i, err)
+			}
+			compiled.selector = selector
+		}
+		router.routes = append(router.routes, compiled)
+	}
+	return router, nil
+}
+
+// route returns the queue of the first route matching the PipelineRun and
+// the config path of that route, e.g. "queueRouting[0]", or empty strings if
+// none matches. Namespace labels are read from inputs; without a Namespace,
+// only selectors matching no labels match. A nil router has no routes.
+func (r *queueRouter) route(plr *tekv1.PipelineRun, inputs cel.Inputs) (queueName, source string) {
+	if r == nil {
+		return "", ""
+	}
+	var namespaceLabels labels.Set
+	if inputs.Namespace != nil {
+		namespaceLabels = inputs.Namespace.GetLabels()
+	}
+	for i, route := range r.routes {
+		if route.matches(plr.Namespace, namespaceLabels) {
+			return route.queueName, fmt.Sprintf("queueRouting[%d]", i)
+		}
+	}
+	return "", ""
+}
+
+// matches reports whether the route applies to the named namespace.
+func (r queueRoute) matches(namespace string, namespaceLabels labels.Set) bool {
+	if len(r.namespaces) > 0 && !slices.ContainsFunc(r.namespaces, func(pattern string) bool {
+		matched, _ := path.Match(pattern, namespace)
+		return matched
+	}) {
+		return false
+	}
+	return r.selector == nil || r.selector.Matches(namespaceLabels)
+}
@@ -26,10 +26,23 @@ const (
 	// QueueLabel is the standard Kueue label used to assign workloads to a LocalQueue.
 	QueueLabel = "kueue.x-k8s.io/queue-name"
 
+	// PriorityClassLabel is the standard Kueue label that sets the
+	// WorkloadPriorityClass of a workload.
+	PriorityClassLabel = "kueue.x-k8s.io/priority-class"
+
+	// MutatedByAnnotation records which CEL rules mutated which labels and
+	// annotations of a PipelineRun, when provenance is enabled.
+	MutatedByAnnotation = "kueue.konflux-ci.dev/mutated-by"
+
 	// ConfigKey is the key within the tekton-kueue-config ConfigMap that holds
 	// the YAML configuration.
 	ConfigKey = "config.yaml"
 
 	// ConfigMapName is the name of the ConfigMap that configures the webhook.
 	ConfigMapName = "tekton-kueue-config"
+
+	// ConfigFragmentLabel marks, with the value "true", ConfigMaps holding a
+	// config fragment under ConfigKey, merged into the tekton-kueue-config
+	// ConfigMap.
+	ConfigFragmentLabel = "tekton-kueue.konflux-ci.dev/config-fragment"
 )
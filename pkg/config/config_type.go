@@ -17,13 +17,42 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// APIVersion is the current version of the configuration format.
+	APIVersion = "tekton-kueue.konflux-ci.dev/v1alpha1"
+
+	// Kind is the kind of the configuration.
+	Kind = "Config"
+
+	// FragmentKind is the kind of a configuration fragment.
+	FragmentKind = "ConfigFragment"
+)
+
 // Config defines the webhook behavior, loaded from the tekton-kueue-config
 // ConfigMap under the "config.yaml" key.
 type Config struct {
+	// TypeMeta identifies the version of the configuration format. A
+	// configuration that sets them is validated strictly; one that doesn't
+	// is read as the unversioned format and converted.
+	metav1.TypeMeta `json:",inline"`
+
 	// QueueName is the Kueue LocalQueue that PipelineRuns are assigned to.
 	// This is set as the "kueue.x-k8s.io/queue-name" label on each PipelineRun.
 	QueueName string `json:"queueName,omitempty"`
 
+	// QueueRouting assigns PipelineRuns to LocalQueues by namespace. The
+	// first matching route wins; PipelineRuns that match no route are
+	// assigned to QueueName.
+	QueueRouting []QueueRoute `json:"queueRouting,omitempty"`
+
 	// MultiKueueOverride, when true, sets the PipelineRun's managedBy field
 	// to "kueue.x-k8s.io/multikueue", enabling Kueue to dispatch the
 	// PipelineRun to a remote worker cluster.
@@ -31,12 +60,303 @@ type Config struct {
 
 	// CEL contains optional CEL expressions for dynamic PipelineRun mutation.
 	CEL CEL `json:"cel,omitempty"`
+
+	// Tables are named lookup tables that CEL expressions read with
+	// lookup() and lookupOr(), so that mappings such as namespace to tier
+	// live in data rather than in expressions.
+	Tables map[string]Table `json:"tables,omitempty"`
+
+	// Profiles are named sets of mutations that CEL expressions apply with
+	// profile(), so that a resource footprint is defined once and referred
+	// to by name.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// ConfigFragment contributes rules, lookup tables and queue routes to a
+// Config, so that teams can own their rules without editing a shared file.
+// Fragments are loaded from ConfigMaps labelled
+// "tekton-kueue.konflux-ci.dev/config-fragment: true" under the "config.yaml"
+// key, and always have an apiVersion and kind.
+type ConfigFragment struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Priority orders the fragments: they are merged into the Config in
+	// ascending order of priority, then of name, after the Config's own
+	// rules and routes. Defaults to 0.
+	Priority int32 `json:"priority,omitempty"`
+
+	// Rules are named rules, appended to the cel.rules of the Config. A
+	// rule name must be unique across the Config and all fragments.
+	Rules []Rule `json:"rules,omitempty"`
+
+	// Tables are named lookup tables, added to the tables of the Config. A
+	// table name must be unique across the Config and all fragments.
+	Tables map[string]Table `json:"tables,omitempty"`
+
+	// QueueRouting entries are appended to the queueRouting of the Config.
+	QueueRouting []QueueRoute `json:"queueRouting,omitempty"`
+}
+
+// Profile is a named set of resource requests, optionally with a priority
+// class and annotations, applied together by profile().
+type Profile struct {
+	// Resources maps resource names, as passed to resource(), to the
+	// quantity requested.
+	Resources map[string]resource.Quantity `json:"resources,omitempty"`
+
+	// PriorityClass, if set, is the WorkloadPriorityClass applied like
+	// priority().
+	PriorityClass string `json:"priorityClass,omitempty"`
+
+	// Annotations are applied like annotation().
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// QueueRoute assigns the PipelineRuns of matching namespaces to a LocalQueue.
+// A namespace matches if it matches Namespaces, when set, and
+// NamespaceSelector, when set. At least one of them must be set.
+type QueueRoute struct {
+	// Namespaces lists namespace names or glob patterns such as "tenant-*".
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects namespaces by label.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// QueueName is the LocalQueue that matching PipelineRuns are assigned to.
+	QueueName string `json:"queueName"`
+}
+
+// Table maps keys to values. All values of a table have the same type.
+type Table map[string]TableValue
+
+// TableValueType is the type of a table value.
+type TableValueType string
+
+const (
+	// TableValueTypeString is a string value.
+	TableValueTypeString TableValueType = "string"
+
+	// TableValueTypeInt is an integer value.
+	TableValueTypeInt TableValueType = "int"
+
+	// TableValueTypeList is a list of strings.
+	TableValueTypeList TableValueType = "list"
+)
+
+// TableValue is a table value: a string, an integer or a list of strings.
+type TableValue struct {
+	Type      TableValueType
+	StringVal string
+	IntVal    int64
+	ListVal   []string
+}
+
+// UnmarshalJSON decodes a string, an integer or a list of strings.
+func (v *TableValue) UnmarshalJSON(data []byte) error {
+	var stringVal string
+	if err := json.Unmarshal(data, &stringVal); err == nil {
+		*v = TableValue{Type: TableValueTypeString, StringVal: stringVal}
+		return nil
+	}
+	var intVal int64
+	if err := json.Unmarshal(data, &intVal); err == nil {
+		*v = TableValue{Type: TableValueTypeInt, IntVal: intVal}
+		return nil
+	}
+	var listVal []string
+	if err := json.Unmarshal(data, &listVal); err == nil && listVal != nil {
+		*v = TableValue{Type: TableValueTypeList, ListVal: listVal}
+		return nil
+	}
+	return fmt.Errorf("table value %s must be a string, an integer or a list of strings", data)
 }
 
-// CEL holds a list of CEL expressions that are evaluated against each
-// PipelineRun during webhook admission. Expressions can set annotations,
-// labels, or resource requests based on PipelineRun properties.
+// MarshalJSON encodes the value according to its type.
+func (v TableValue) MarshalJSON() ([]byte, error) {
+	switch v.Type {
+	case TableValueTypeInt:
+		return json.Marshal(v.IntVal)
+	case TableValueTypeList:
+		return json.Marshal(v.ListVal)
+	default:
+		return json.Marshal(v.StringVal)
+	}
+}
+
+// CEL holds the CEL rules that are evaluated against each PipelineRun
+// during webhook admission. Rules can set annotations, labels, or resource
+// requests based on PipelineRun properties.
 // See the internal/cel package for available functions and variables.
 type CEL struct {
+	// Expressions are anonymous rules without a condition, evaluated in
+	// order before Rules. Each one is named after its position in the list,
+	// e.g. "expressions[0]".
 	Expressions []string `json:"expressions,omitempty"`
+
+	// Rules are named rules, evaluated in order after Expressions.
+	Rules []Rule `json:"rules,omitempty"`
+
+	// Variables are named CEL expressions shared by all rules, which read
+	// them as variables.<name>. A variable may use the variables declared
+	// before it. Each one is evaluated at most once per PipelineRun, the
+	// first time a rule uses it.
+	Variables []Variable `json:"variables,omitempty"`
+
+	// CostLimit is the CEL cost budget of every expression and when
+	// condition. When set, expressions whose estimated worst-case cost is
+	// over the budget are rejected when the configuration is loaded, and
+	// evaluations that go over it at runtime are stopped. Zero disables
+	// both checks.
+	CostLimit uint64 `json:"costLimit,omitempty"`
+
+	// EvaluationTimeout bounds the time spent evaluating all rules for a
+	// single PipelineRun, e.g. "200ms". Defaults to 5s, below the webhook
+	// timeout, since the deadline of the admission request is not available
+	// to the webhook. Zero disables the timeout.
+	EvaluationTimeout *metav1.Duration `json:"evaluationTimeout,omitempty"`
+
+	// ConflictPolicy decides what happens when several mutations set or
+	// remove the same label or annotation with different results. Defaults
+	// to lastWins.
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+
+	// PriorityClasses lists WorkloadPriorityClass names from highest to
+	// lowest priority. It is required by the highestPriority conflict
+	// policy.
+	PriorityClasses []string `json:"priorityClasses,omitempty"`
+
+	// ResourceAggregation maps resource names, as passed to resource(), to
+	// the way repeated values of the resource are combined. Resources that
+	// are not listed are summed. Functions such as resourceMax() override
+	// it for a single mutation.
+	ResourceAggregation map[string]ResourceAggregation `json:"resourceAggregation,omitempty"`
+
+	// ExistingResources decides how resource values combine with resource
+	// annotations already set on the PipelineRun. Defaults to override, so
+	// that users can't inflate the resources requested by CEL; set remove to
+	// also drop the resources that no rule requests.
+	ExistingResources ExistingResourcePolicy `json:"existingResources,omitempty"`
+
+	// Provenance, when true, records which rule mutated each label and
+	// annotation, together with the configuration generation, in the
+	// "kueue.konflux-ci.dev/mutated-by" annotation.
+	Provenance bool `json:"provenance,omitempty"`
+}
+
+// ResourceAggregation decides how several values of the same resource are
+// combined into its annotation.
+type ResourceAggregation string
+
+const (
+	// ResourceAggregationSum adds all values up.
+	ResourceAggregationSum ResourceAggregation = "sum"
+
+	// ResourceAggregationMax keeps the largest value.
+	ResourceAggregationMax ResourceAggregation = "max"
+
+	// ResourceAggregationMin keeps the smallest value.
+	ResourceAggregationMin ResourceAggregation = "min"
+
+	// ResourceAggregationSet keeps the value evaluated last.
+	ResourceAggregationSet ResourceAggregation = "set"
+)
+
+// ExistingResourcePolicy decides what happens to resource annotations that
+// are already set on the PipelineRun when it is admitted.
+type ExistingResourcePolicy string
+
+const (
+	// ExistingResourcesAggregate combines an existing value with the values
+	// from CEL like one more value of the resource. User-supplied values
+	// therefore still count towards the request. It was the behavior before
+	// existingResources was introduced.
+	ExistingResourcesAggregate ExistingResourcePolicy = "aggregate"
+
+	// ExistingResourcesOverride replaces an existing value with the value
+	// from CEL. Resources that no rule requests keep their existing value.
+	// This is the default.
+	ExistingResourcesOverride ExistingResourcePolicy = "override"
+
+	// ExistingResourcesRemove removes every existing resource annotation, so
+	// that only CEL decides the resources of a PipelineRun.
+	ExistingResourcesRemove ExistingResourcePolicy = "remove"
+)
+
+// ConflictPolicy decides which mutation is applied when several mutations
+// write the same label or annotation.
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyLastWins applies the mutation evaluated last.
+	ConflictPolicyLastWins ConflictPolicy = "lastWins"
+
+	// ConflictPolicyFirstWins applies the mutation evaluated first.
+	ConflictPolicyFirstWins ConflictPolicy = "firstWins"
+
+	// ConflictPolicyError rejects the PipelineRun.
+	ConflictPolicyError ConflictPolicy = "error"
+
+	// ConflictPolicyHighestPriority applies the priority class that comes
+	// first in PriorityClasses to the "kueue.x-k8s.io/priority-class"
+	// label. Conflicts on other keys are resolved as with lastWins.
+	ConflictPolicyHighestPriority ConflictPolicy = "highestPriority"
+)
+
+// Rule is a named CEL rule with an optional guard condition.
+type Rule struct {
+	// Name identifies the rule in logs, metrics and error messages. It must
+	// be unique and a valid label value.
+	Name string `json:"name"`
+
+	// Description documents what the rule is for.
+	Description string `json:"description,omitempty"`
+
+	// When is an optional CEL expression returning a bool. Mutations are
+	// only evaluated when it returns true.
+	When string `json:"when,omitempty"`
+
+	// Mutations is a CEL expression returning a MutationRequest or a list
+	// of MutationRequests, like an entry of Expressions.
+	Mutations string `json:"mutations"`
+
+	// OnError decides what happens when the when condition or the
+	// mutations fail to evaluate. Defaults to fail.
+	OnError OnErrorPolicy `json:"onError,omitempty"`
+
+	// Fallback is a CEL expression returning the mutations applied instead
+	// of Mutations when the rule fails. It is required by, and only
+	// allowed with, the default OnError policy.
+	Fallback string `json:"fallback,omitempty"`
+
+	// Shadow, when true, evaluates the rule after the live rules without
+	// applying its mutations. Its results are compared with the live
+	// results in logs and metrics, so that a rule can be tried on real
+	// traffic before it is enabled.
+	Shadow bool `json:"shadow,omitempty"`
+}
+
+// Variable is a named CEL expression shared by all rules.
+type Variable struct {
+	// Name is the field of the variables object holding the result. It
+	// must be a CEL identifier and unique.
+	Name string `json:"name"`
+
+	// Expression is a CEL expression of any type.
+	Expression string `json:"expression"`
 }
+
+// OnErrorPolicy decides what happens when a rule fails to evaluate.
+type OnErrorPolicy string
+
+const (
+	// OnErrorFail rejects the PipelineRun.
+	OnErrorFail OnErrorPolicy = "fail"
+
+	// OnErrorSkip logs the failure and continues without the rule's
+	// mutations.
+	OnErrorSkip OnErrorPolicy = "skip"
+
+	// OnErrorDefault logs the failure and applies the rule's Fallback
+	// mutations instead.
+	OnErrorDefault OnErrorPolicy = "default"
+)
@@ -21,20 +21,67 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
+	"time"
 
 	webhookv1 "github.com/konflux-ci/tekton-kueue/internal/webhook/v1"
+	"github.com/konflux-ci/tekton-kueue/pkg/config"
 	tekv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 )
 
+// Option configures optional inputs of MutatePipelineRun.
+type Option func(*options)
+
+type options struct {
+	namespaceFile string
+	now           time.Time
+	configFiles   []string
+}
+
+// WithConfigFiles reads the configuration from files, in addition to the
+// config.yaml of the configDir if set. One of the files, or the config.yaml,
+// must hold the config and the others config fragments, merged the same way
+// the webhook merges fragment ConfigMaps.
+func WithConfigFiles(configFiles ...string) Option {
+	return func(o *options) {
+		o.configFiles = append(o.configFiles, configFiles...)
+	}
+}
+
+// WithNamespaceFile reads the Namespace of the PipelineRun from a YAML or JSON
+// manifest, so that its labels and annotations are exposed to CEL expressions
+// the same way the webhook exposes them.
+func WithNamespaceFile(namespaceFile string) Option {
+	return func(o *options) {
+		o.namespaceFile = namespaceFile
+	}
+}
+
+// WithNow fixes the time exposed to CEL expressions, e.g. through the now
+// variable and inTimeWindow(), so that time-dependent rules give reproducible
+// results. Without it the current time is used.
+func WithNow(now time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
 // MutatePipelineRun reads a PipelineRun from a file, applies mutations based on the config,
-// and returns the mutated PipelineRun as YAML bytes.
-func MutatePipelineRun(pipelineRunFile, configDir string) ([]byte, error) {
+// and returns the mutated PipelineRun as YAML bytes. The configDir may be empty
+// if the configuration is passed with WithConfigFiles.
+func MutatePipelineRun(pipelineRunFile, configDir string, opts ...Option) ([]byte, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	// Validate inputs
 	if pipelineRunFile == "" {
 		return nil, fmt.Errorf("pipelineRunFile cannot be empty")
 	}
-	if configDir == "" {
+	if configDir == "" && len(o.configFiles) == 0 {
 		return nil, fmt.Errorf("configDir cannot be empty")
 	}
 
@@ -51,12 +98,30 @@ func MutatePipelineRun(pipelineRunFile, configDir string) ([]byte, error) {
 	}
 
 	// Load config and create defaulter
-	cfgStore, err := LoadDefaulter(configDir)
+	configFiles := o.configFiles
+	if configDir != "" {
+		configFiles = append([]string{path.Join(configDir, "config.yaml")}, configFiles...)
+	}
+	cfgStore, err := LoadConfigFiles(configFiles...)
 	if err != nil {
 		return nil, err
 	}
 
-	defaulter, err := webhookv1.NewCustomDefaulter(cfgStore)
+	var namespaces webhookv1.NamespaceGetter
+	if o.namespaceFile != "" {
+		namespace, err := loadNamespace(o.namespaceFile, pipelineRun.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		namespaces = staticNamespaceGetter{namespace: namespace}
+	}
+
+	var defaulterOpts []webhookv1.DefaulterOption
+	if !o.now.IsZero() {
+		defaulterOpts = append(defaulterOpts, webhookv1.WithNow(func() time.Time { return o.now }))
+	}
+
+	defaulter, err := webhookv1.NewCustomDefaulter(cfgStore, namespaces, defaulterOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create custom defaulter: %w", err)
 	}
@@ -82,16 +147,80 @@ func LoadDefaulter(configDir string) (*webhookv1.ConfigStore, error) {
 		return nil, fmt.Errorf("configDir cannot be empty")
 	}
 
-	configPath := path.Join(configDir, "config.yaml")
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file %q: %w", configPath, err)
+	return LoadConfigFiles(path.Join(configDir, "config.yaml"))
+}
+
+// LoadConfigFiles loads the webhook configuration from a config file and
+// any number of config fragment files, and returns a ConfigStore. Files are
+// told apart by their kind. Like a fragment ConfigMap, a fragment file is
+// named after its base name without extension, e.g. "team-a" for
+// "rules/team-a.yaml".
+func LoadConfigFiles(configFiles ...string) (*webhookv1.ConfigStore, error) {
+	var (
+		configPath string
+		data       []byte
+		fragments  []webhookv1.ConfigFragment
+	)
+	for _, configFile := range configFiles {
+		fileData, err := os.ReadFile(configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %q: %w", configFile, err)
+		}
+		var typeMeta metav1.TypeMeta
+		if err := yaml.Unmarshal(fileData, &typeMeta); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %q: %w", configFile, err)
+		}
+		if typeMeta.Kind == config.FragmentKind {
+			name := strings.TrimSuffix(path.Base(configFile), path.Ext(configFile))
+			fragments = append(fragments, webhookv1.ConfigFragment{Name: name, Data: fileData})
+			continue
+		}
+		if configPath != "" {
+			return nil, fmt.Errorf("config files %q and %q both hold a config, the others must be config fragments",
+				configPath, configFile)
+		}
+		configPath, data = configFile, fileData
+	}
+	if configPath == "" {
+		return nil, fmt.Errorf("no config file among %q, one of them must hold the config", configFiles)
 	}
 
 	cfgStore := &webhookv1.ConfigStore{}
-	if err := cfgStore.Update(data); err != nil {
+	if err := cfgStore.Update(data, fragments...); err != nil {
 		return nil, fmt.Errorf("failed to update config: %w", err)
 	}
 
 	return cfgStore, nil
 }
+
+// loadNamespace reads a Namespace manifest. If the PipelineRun sets its
+// namespace, it must match the name of the Namespace.
+func loadNamespace(namespaceFile, pipelineRunNamespace string) (*metav1.PartialObjectMetadata, error) {
+	data, err := os.ReadFile(namespaceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Namespace file %q: %w", namespaceFile, err)
+	}
+
+	namespace := &metav1.PartialObjectMetadata{}
+	if err := yaml.Unmarshal(data, namespace); err != nil {
+		return nil, fmt.Errorf("failed to parse Namespace YAML: %w", err)
+	}
+	if namespace.Kind != "" && namespace.Kind != "Namespace" {
+		return nil, fmt.Errorf("namespace file %q contains a %s, not a Namespace", namespaceFile, namespace.Kind)
+	}
+	if pipelineRunNamespace != "" && namespace.Name != pipelineRunNamespace {
+		return nil, fmt.Errorf("namespace file %q describes namespace %q, but the PipelineRun is in namespace %q",
+			namespaceFile, namespace.Name, pipelineRunNamespace)
+	}
+
+	return namespace, nil
+}
+
+// staticNamespaceGetter returns the same Namespace for every lookup.
+type staticNamespaceGetter struct {
+	namespace metav1.Object
+}
+
+func (g staticNamespaceGetter) GetNamespace(_ context.Context, _ string) (metav1.Object, error) {
+	return g.namespace, nil
+}
@@ -19,7 +19,9 @@ package mutate
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/konflux-ci/tekton-kueue/pkg/common"
 	. "github.com/onsi/ginkgo/v2"
@@ -176,13 +178,13 @@ spec: {}
 
 	Context("with CEL evaluation error", func() {
 		It("should return InternalServerError when a CEL expression fails at runtime", func() {
-			// Write config with a CEL expression that accesses a non-existent field
+			// Write config with a CEL expression that accesses a missing label
 			configPath := filepath.Join(tmpDir, "config.yaml")
 			configContent := `
 queueName: "test-queue"
 cel:
   expressions:
-    - 'annotation("key", pipelineRun.doesNotExist)'
+    - 'annotation("key", pipelineRun.metadata.labels["does-not-exist"])'
 `
 			Expect(os.WriteFile(configPath, []byte(configContent), 0644)).To(Succeed())
 
@@ -198,6 +200,202 @@ cel:
 		})
 	})
 
+	Context("with a namespace file", func() {
+		const namespaceConfig = `
+queueName: "test-queue"
+cel:
+  expressions:
+    - 'label("tenant", "tenant" in namespaceObject.labels ? namespaceObject.labels["tenant"] : "none")'
+`
+
+		BeforeEach(func() {
+			configPath := filepath.Join(tmpDir, "config.yaml")
+			Expect(os.WriteFile(configPath, []byte(namespaceConfig), 0644)).To(Succeed())
+
+			namespacePath := filepath.Join(tmpDir, "namespace.yaml")
+			namespaceContent := `apiVersion: v1
+kind: Namespace
+metadata:
+  name: tenant-ns
+  labels:
+    tenant: team-a
+`
+			Expect(os.WriteFile(namespacePath, []byte(namespaceContent), 0644)).To(Succeed())
+		})
+
+		It("should expose the namespace labels to CEL expressions", func() {
+			plrPath := filepath.Join(tmpDir, "pipelinerun.yaml")
+			Expect(os.WriteFile(plrPath, []byte(validPipelineRunYAML), 0644)).To(Succeed())
+
+			mutatedData, err := MutatePipelineRun(plrPath, tmpDir, WithNamespaceFile(filepath.Join(tmpDir, "namespace.yaml")))
+			Expect(err).NotTo(HaveOccurred())
+
+			var pipelineRun tekv1.PipelineRun
+			Expect(yaml.Unmarshal(mutatedData, &pipelineRun)).To(Succeed())
+			Expect(pipelineRun.Labels["tenant"]).To(Equal("team-a"))
+		})
+
+		It("should expose an empty namespace without a namespace file", func() {
+			plrPath := filepath.Join(tmpDir, "pipelinerun.yaml")
+			Expect(os.WriteFile(plrPath, []byte(validPipelineRunYAML), 0644)).To(Succeed())
+
+			mutatedData, err := MutatePipelineRun(plrPath, tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			var pipelineRun tekv1.PipelineRun
+			Expect(yaml.Unmarshal(mutatedData, &pipelineRun)).To(Succeed())
+			Expect(pipelineRun.Labels["tenant"]).To(Equal("none"))
+		})
+
+		It("should reject a namespace file for another namespace", func() {
+			plrContent := `apiVersion: tekton.dev/v1
+kind: PipelineRun
+metadata:
+  name: test-pipelinerun
+  namespace: other-ns
+spec:
+  pipelineRef:
+    name: my-pipeline
+`
+			plrPath := filepath.Join(tmpDir, "pipelinerun.yaml")
+			Expect(os.WriteFile(plrPath, []byte(plrContent), 0644)).To(Succeed())
+
+			_, err := MutatePipelineRun(plrPath, tmpDir, WithNamespaceFile(filepath.Join(tmpDir, "namespace.yaml")))
+			Expect(err).To(MatchError(ContainSubstring(`describes namespace "tenant-ns"`)))
+		})
+	})
+
+	Context("with queue routing", func() {
+		const routingConfig = `
+queueName: "default-queue"
+queueRouting:
+  - namespaces: ["release-*"]
+    queueName: release-queue
+  - namespaceSelector:
+      matchLabels:
+        tier: gold
+    queueName: gold-queue
+`
+
+		BeforeEach(func() {
+			configPath := filepath.Join(tmpDir, "config.yaml")
+			Expect(os.WriteFile(configPath, []byte(routingConfig), 0644)).To(Succeed())
+
+			namespacePath := filepath.Join(tmpDir, "namespace.yaml")
+			namespaceContent := `apiVersion: v1
+kind: Namespace
+metadata:
+  name: tenant-ns
+  labels:
+    tier: gold
+`
+			Expect(os.WriteFile(namespacePath, []byte(namespaceContent), 0644)).To(Succeed())
+		})
+
+		DescribeTable("should assign the queue of the first matching route",
+			func(namespace string, withNamespaceFile bool, expectedQueue string) {
+				plrContent := strings.Replace(validPipelineRunYAML, "  name: test-pipelinerun\n",
+					"  name: test-pipelinerun\n  namespace: "+namespace+"\n", 1)
+				plrPath := filepath.Join(tmpDir, "pipelinerun.yaml")
+				Expect(os.WriteFile(plrPath, []byte(plrContent), 0644)).To(Succeed())
+
+				var opts []Option
+				if withNamespaceFile {
+					opts = append(opts, WithNamespaceFile(filepath.Join(tmpDir, "namespace.yaml")))
+				}
+				mutatedData, err := MutatePipelineRun(plrPath, tmpDir, opts...)
+				Expect(err).NotTo(HaveOccurred())
+
+				var pipelineRun tekv1.PipelineRun
+				Expect(yaml.Unmarshal(mutatedData, &pipelineRun)).To(Succeed())
+				Expect(pipelineRun.Labels[common.QueueLabel]).To(Equal(expectedQueue))
+			},
+			Entry("by namespace name", "release-prod", false, "release-queue"),
+			Entry("by namespace labels", "tenant-ns", true, "gold-queue"),
+			Entry("without namespace labels", "tenant-ns", false, "default-queue"),
+		)
+	})
+
+	Context("with config fragment files", func() {
+		BeforeEach(func() {
+			Expect(os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(`queueName: "test-queue"`), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(tmpDir, "team-a.yaml"), []byte(`
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: ConfigFragment
+priority: 10
+rules:
+  - name: team-a
+    mutations: 'label("team", "team-a")'
+`), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(tmpDir, "team-b.yaml"), []byte(`
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: ConfigFragment
+rules:
+  - name: team-b
+    mutations: 'label("team", "team-b")'
+queueRouting:
+  - namespaces: ["*"]
+    queueName: team-b-queue
+`), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(tmpDir, "pipelinerun.yaml"), []byte(validPipelineRunYAML), 0644)).To(Succeed())
+		})
+
+		It("should merge the fragments into the config", func() {
+			mutatedData, err := MutatePipelineRun(filepath.Join(tmpDir, "pipelinerun.yaml"), "", WithConfigFiles(
+				filepath.Join(tmpDir, "team-a.yaml"),
+				filepath.Join(tmpDir, "config.yaml"),
+				filepath.Join(tmpDir, "team-b.yaml"),
+			))
+			Expect(err).NotTo(HaveOccurred())
+
+			var pipelineRun tekv1.PipelineRun
+			Expect(yaml.Unmarshal(mutatedData, &pipelineRun)).To(Succeed())
+			// team-a has the higher priority, so it is merged last and wins
+			Expect(pipelineRun.Labels).To(HaveKeyWithValue("team", "team-a"))
+			Expect(pipelineRun.Labels).To(HaveKeyWithValue(common.QueueLabel, "team-b-queue"))
+		})
+
+		It("should merge the fragments into the config of the config dir", func() {
+			mutatedData, err := MutatePipelineRun(filepath.Join(tmpDir, "pipelinerun.yaml"), tmpDir,
+				WithConfigFiles(filepath.Join(tmpDir, "team-b.yaml")))
+			Expect(err).NotTo(HaveOccurred())
+
+			var pipelineRun tekv1.PipelineRun
+			Expect(yaml.Unmarshal(mutatedData, &pipelineRun)).To(Succeed())
+			Expect(pipelineRun.Labels).To(HaveKeyWithValue("team", "team-b"))
+		})
+	})
+
+	Context("with a fixed time", func() {
+		const timeConfig = `
+queueName: "test-queue"
+cel:
+  expressions:
+    - 'priority(inTimeWindow("Mon-Fri 08:00-18:00", "Europe/Prague") ? "konflux-low" : "konflux-default")'
+`
+
+		BeforeEach(func() {
+			configPath := filepath.Join(tmpDir, "config.yaml")
+			Expect(os.WriteFile(configPath, []byte(timeConfig), 0644)).To(Succeed())
+		})
+
+		DescribeTable("should evaluate time windows at the given time",
+			func(now time.Time, expectedPriority string) {
+				plrPath := filepath.Join(tmpDir, "pipelinerun.yaml")
+				Expect(os.WriteFile(plrPath, []byte(validPipelineRunYAML), 0644)).To(Succeed())
+
+				mutatedData, err := MutatePipelineRun(plrPath, tmpDir, WithNow(now))
+				Expect(err).NotTo(HaveOccurred())
+
+				var pipelineRun tekv1.PipelineRun
+				Expect(yaml.Unmarshal(mutatedData, &pipelineRun)).To(Succeed())
+				Expect(pipelineRun.Labels["kueue.x-k8s.io/priority-class"]).To(Equal(expectedPriority))
+			},
+			Entry("during working hours", time.Date(2025, 6, 2, 9, 30, 0, 0, time.UTC), "konflux-low"),
+			Entry("at night", time.Date(2025, 6, 2, 22, 0, 0, 0, time.UTC), "konflux-default"),
+		)
+	})
+
 	Context("with invalid inputs", func() {
 		It("should reject empty pipelineRunFile", func() {
 			_, err := MutatePipelineRun("", "/tmp")
@@ -280,3 +478,65 @@ var _ = Describe("LoadDefaulter", func() {
 		Expect(err).To(HaveOccurred())
 	})
 })
+
+var _ = Describe("LoadConfigFiles", func() {
+	var (
+		tmpDir string
+	)
+
+	const fragment = `
+apiVersion: tekton-kueue.konflux-ci.dev/v1alpha1
+kind: ConfigFragment
+rules:
+  - name: shared
+    mutations: 'label("team", "team-a")'
+`
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "mutate-test")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(`queueName: "test-queue"`), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(tmpDir, "team-a.yaml"), []byte(fragment), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	It("should load a config and its fragments", func() {
+		cfgStore, err := LoadConfigFiles(filepath.Join(tmpDir, "config.yaml"), filepath.Join(tmpDir, "team-a.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+
+		cfg, _ := cfgStore.GetConfigAndMutators()
+		Expect(cfg.CEL.Rules).To(HaveLen(1))
+		Expect(cfg.CEL.Rules[0].Name).To(Equal("shared"))
+	})
+
+	It("should name fragments after their files", func() {
+		Expect(os.MkdirAll(filepath.Join(tmpDir, "rules"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(tmpDir, "rules", "team-b.yml"), []byte(fragment), 0644)).To(Succeed())
+
+		_, err := LoadConfigFiles(filepath.Join(tmpDir, "config.yaml"),
+			filepath.Join(tmpDir, "team-a.yaml"), filepath.Join(tmpDir, "rules", "team-b.yml"))
+		Expect(err).To(MatchError(ContainSubstring(
+			`config fragment "team-b": rule "shared" is already defined by config fragment "team-a"`)))
+	})
+
+	It("should reject several configs", func() {
+		Expect(os.WriteFile(filepath.Join(tmpDir, "other.yaml"), []byte(`queueName: "other-queue"`), 0644)).To(Succeed())
+
+		_, err := LoadConfigFiles(filepath.Join(tmpDir, "config.yaml"), filepath.Join(tmpDir, "other.yaml"))
+		Expect(err).To(MatchError(ContainSubstring("both hold a config")))
+	})
+
+	It("should reject fragments without a config", func() {
+		_, err := LoadConfigFiles(filepath.Join(tmpDir, "team-a.yaml"))
+		Expect(err).To(MatchError(ContainSubstring("no config file")))
+	})
+
+	It("should reject a missing file", func() {
+		_, err := LoadConfigFiles(filepath.Join(tmpDir, "config.yaml"), filepath.Join(tmpDir, "missing.yaml"))
+		Expect(err).To(MatchError(ContainSubstring("failed to read config file")))
+	})
+})